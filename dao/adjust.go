@@ -0,0 +1,67 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// adjustMaxAttempts is how many times Adjust runs its transaction when it
+// contends with other writers.
+const adjustMaxAttempts = 10
+
+// Adjust adds delta to an integer field, and returns the new value, unless it
+// would end up outside [min, max], in which case ErrConflict is returned. A
+// missing field counts as zero.
+func (c *DAO[Document]) Adjust(ctx context.Context, id, field string, delta, min, max int64) (int64, error) {
+
+	ref := c.c.Collection(c.path).Doc(id)
+
+	var adjusted int64
+	err := c.c.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snapshot, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				cause := fmt.Sprintf("(ID: %s)", id)
+				return e.Wrap(cause, e.ErrNotFound)
+			}
+			return err
+		}
+
+		var current int64
+		value, found := lookup(snapshot.Data(), field)
+		if found {
+			current, found = value.(int64)
+			if !found {
+				cause := fmt.Sprintf("(field '%s' is %T, not an integer)", field, value)
+				return e.Wrap(cause, e.ErrCorrupt)
+			}
+		}
+
+		adjusted = current + delta
+		if adjusted < min || adjusted > max {
+			cause := fmt.Sprintf("(adjusting '%s' by %d to %d is outside [%d, %d])", field, delta, adjusted, min, max)
+			return e.Wrap(cause, e.ErrConflict)
+		}
+
+		fus := []firestore.Update{
+			{
+				Path:  field,
+				Value: adjusted,
+			},
+		}
+		fus = append(fus, c.touched(time.Now())...)
+
+		return tx.Update(ref, fus)
+	}, firestore.MaxAttempts(adjustMaxAttempts))
+	if err != nil {
+		return 0, err
+	}
+
+	return adjusted, nil
+}
@@ -0,0 +1,175 @@
+package dao
+
+import (
+	"context"
+	"sort"
+
+	"cloud.google.com/go/firestore"
+	t "github.com/pergamenum/go-consensus-standards/types"
+	"golang.org/x/sync/errgroup"
+)
+
+type Order struct {
+	Key        string
+	Descending bool
+}
+
+// SearchAny returns the documents matching any of the groups of queries, each
+// group being ANDed like in Search. Every group runs as its own Firestore
+// query, and the results are merged, de-duplicated, sorted by orders and the
+// document ID, and then cut at limit, where zero means no limit. Like with a
+// Firestore order, documents without an order field are left out. With a
+// limit, every group only fetches its own first limit documents, unless it
+// has an inequality on a field other than the first order.
+func (c *DAO[Document]) SearchAny(ctx context.Context, groups [][]t.Query, orders []Order, limit int) ([]Document, error) {
+
+	log := c.log.Named("SearchAny")
+
+	results := make([][]*firestore.DocumentSnapshot, len(groups))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, queries := range groups {
+		i, queries := i, queries
+		g.Go(func() error {
+			snapshots, err := c.groupSnapshots(gctx, queries, orders, limit)
+			results[i] = snapshots
+			return err
+		})
+	}
+	err := g.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var merged []*firestore.DocumentSnapshot
+	for _, snapshots := range results {
+		for _, s := range snapshots {
+			if seen[s.Ref.ID] || !ordered(s.Data(), orders) {
+				continue
+			}
+			seen[s.Ref.ID] = true
+			merged = append(merged, s)
+		}
+	}
+
+	sortSnapshots(merged, orders)
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+
+	return c.decode(log, merged), nil
+}
+
+// groupSnapshots fetches the snapshots of one group. With a limit, the group
+// is ordered the way the merged result is, so that only its first limit
+// snapshots can make it into the result. A group Firestore cannot order that
+// way is fetched without a limit.
+func (c *DAO[Document]) groupSnapshots(ctx context.Context, queries []t.Query, orders []Order, limit int) ([]*firestore.DocumentSnapshot, error) {
+
+	if limit <= 0 {
+		return c.snapshots(ctx, queries, 0)
+	}
+
+	server, client, err := c.split(queries)
+	if err != nil {
+		return nil, err
+	}
+	if !orderable(server, orders) {
+		return c.snapshots(ctx, queries, 0)
+	}
+	fsq, err := c.query(server)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := false
+	for _, o := range orders {
+		direction := firestore.Asc
+		if o.Descending {
+			direction = firestore.Desc
+		}
+		fsq = fsq.OrderBy(o.Key, direction)
+		byID = byID || o.Key == firestore.DocumentID
+	}
+	if !byID {
+		fsq = fsq.OrderBy(firestore.DocumentID, firestore.Asc)
+	}
+
+	if len(client) > 0 {
+		snapshots, err := c.filter(ctx, fsq, client)
+		if err != nil {
+			return nil, err
+		}
+		if len(snapshots) > limit {
+			snapshots = snapshots[:limit]
+		}
+		return snapshots, nil
+	}
+
+	snapshots, err := fsq.Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, queryError(err)
+	}
+
+	return snapshots, nil
+}
+
+// ordered reports whether data has all the order fields.
+func ordered(data map[string]any, orders []Order) bool {
+
+	for _, o := range orders {
+		if o.Key == firestore.DocumentID {
+			continue
+		}
+		_, found := lookup(data, o.Key)
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortSnapshots(snapshots []*firestore.DocumentSnapshot, orders []Order) {
+
+	data := make(map[*firestore.DocumentSnapshot]map[string]any, len(snapshots))
+	for _, s := range snapshots {
+		data[s] = s.Data()
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		a, b := snapshots[i], snapshots[j]
+		for _, o := range orders {
+			va, _ := lookup(data[a], o.Key)
+			vb, _ := lookup(data[b], o.Key)
+			if o.Key == firestore.DocumentID {
+				va, vb = a.Ref.ID, b.Ref.ID
+			}
+			order := sortOrder(va, vb)
+			if o.Descending {
+				order = -order
+			}
+			if order != 0 {
+				return order < 0
+			}
+		}
+		return a.Ref.ID < b.Ref.ID
+	})
+}
+
+// orderable reports whether a Firestore query filtering by server can be
+// ordered by orders, as its inequality field has to be ordered by first.
+func orderable(server []t.Query, orders []Order) bool {
+
+	first := firestore.DocumentID
+	if len(orders) > 0 {
+		first = orders[0].Key
+	}
+	for _, q := range server {
+		if inequality(q.Operator) && q.Key != first {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,41 @@
+package dao
+
+import (
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+func TestOrderable(tt *testing.T) {
+
+	eq := t.Query{Key: "tenant", Operator: "EQ", Value: "acme"}
+	ageGT := t.Query{Key: "age", Operator: "GT", Value: 18}
+	idGE := t.Query{Key: firestore.DocumentID, Operator: "GE", Value: "a"}
+	byAge := Order{Key: "age"}
+	byName := Order{Key: "name", Descending: true}
+
+	tests := []struct {
+		name   string
+		server []t.Query
+		orders []Order
+		want   bool
+	}{
+		{"equality only", []t.Query{eq}, []Order{byName}, true},
+		{"inequality field first", []t.Query{eq, ageGT}, []Order{byAge, byName}, true},
+		{"inequality field second", []t.Query{ageGT}, []Order{byName, byAge}, false},
+		{"inequality on another field", []t.Query{ageGT}, []Order{byName}, false},
+		{"inequality without orders", []t.Query{ageGT}, nil, false},
+		{"ID inequality without orders", []t.Query{idGE}, nil, true},
+		{"ID inequality with orders", []t.Query{idGE}, []Order{byName}, false},
+	}
+
+	for _, test := range tests {
+		tt.Run(test.name, func(tt *testing.T) {
+			got := orderable(test.server, test.orders)
+			if got != test.want {
+				tt.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
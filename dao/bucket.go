@@ -0,0 +1,174 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	t "github.com/pergamenum/go-consensus-standards/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxBuckets bounds the number of count queries a single Buckets call makes,
+// of which at most bucketConcurrency run at the same time.
+const (
+	maxBuckets        = 1000
+	bucketConcurrency = 10
+)
+
+type BucketQuery struct {
+	// Field holds the timestamps to bucket by, like the managed "created".
+	Field string
+	// From is the start of the first bucket, and To the end of the last.
+	From time.Time
+	To   time.Time
+	// Interval is the width of each bucket. Multiples of 24 hours step by
+	// calendar days in Location, so that days stay aligned across DST.
+	Interval time.Duration
+	// Location defaults to UTC.
+	Location *time.Location
+	// Sum lists numeric fields to sum per bucket. Summing needs a streamed
+	// scan of the documents, instead of a count query per bucket.
+	Sum     []string
+	Queries []t.Query
+}
+
+type Bucket struct {
+	Start time.Time          `json:"start"`
+	End   time.Time          `json:"end"`
+	Count int64              `json:"count"`
+	Sums  map[string]float64 `json:"sums,omitempty"`
+}
+
+// Buckets counts, and optionally sums, the documents matching bq.Queries per
+// time bucket of bq.Field.
+func (c *DAO[Document]) Buckets(ctx context.Context, bq BucketQuery) ([]Bucket, error) {
+
+	buckets, err := bucketRange(bq)
+	if err != nil {
+		return nil, err
+	}
+
+	server, client, err := c.split(bq.Queries)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bq.Sum) == 0 && len(client) == 0 {
+		err = c.countBuckets(ctx, bq.Field, server, buckets)
+	} else {
+		err = c.scanBuckets(ctx, bq, server, client, buckets)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+func bucketRange(bq BucketQuery) ([]Bucket, error) {
+
+	if bq.Interval <= 0 || !bq.From.Before(bq.To) {
+		cause := fmt.Sprintf("(invalid buckets of %s from %s to %s)", bq.Interval, bq.From, bq.To)
+		return nil, e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	loc := bq.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	const day = 24 * time.Hour
+	next := func(start time.Time) time.Time {
+		if bq.Interval%day == 0 {
+			return start.AddDate(0, 0, int(bq.Interval/day))
+		}
+		return start.Add(bq.Interval)
+	}
+
+	var buckets []Bucket
+	for start := bq.From.In(loc); start.Before(bq.To); start = next(start) {
+		if len(buckets) == maxBuckets {
+			cause := fmt.Sprintf("(more than %d buckets)", maxBuckets)
+			return nil, e.Wrap(cause, e.ErrBadRequest)
+		}
+		end := next(start)
+		if end.After(bq.To) {
+			end = bq.To.In(loc)
+		}
+		buckets = append(buckets, Bucket{
+			Start: start,
+			End:   end,
+		})
+	}
+
+	return buckets, nil
+}
+
+func (c *DAO[Document]) countBuckets(ctx context.Context, field string, queries []t.Query, buckets []Bucket) error {
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(bucketConcurrency)
+	for i := range buckets {
+		b := &buckets[i]
+		ranged := append(queries[:len(queries):len(queries)],
+			t.Query{Key: field, Operator: "GE", Value: b.Start},
+			t.Query{Key: field, Operator: "LT", Value: b.End},
+		)
+		fsq, err := c.query(ranged)
+		if err != nil {
+			return err
+		}
+		g.Go(func() error {
+			n, err := count(gctx, fsq)
+			b.Count = n
+			return err
+		})
+	}
+
+	return g.Wait()
+}
+
+func (c *DAO[Document]) scanBuckets(ctx context.Context, bq BucketQuery, server, client []t.Query, buckets []Bucket) error {
+
+	ranged := append(server[:len(server):len(server)],
+		t.Query{Key: bq.Field, Operator: "GE", Value: bq.From},
+		t.Query{Key: bq.Field, Operator: "LT", Value: bq.To},
+	)
+	fsq, err := c.query(ranged)
+	if err != nil {
+		return err
+	}
+
+	for i := range buckets {
+		buckets[i].Sums = map[string]float64{}
+	}
+
+	return c.scan(ctx, fsq, client, c.cfg.maxScanned, func(s *firestore.DocumentSnapshot) {
+		data := s.Data()
+		value, _ := lookup(data, bq.Field)
+		ts, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		i := sort.Search(len(buckets), func(i int) bool {
+			return buckets[i].End.After(ts)
+		})
+		if i == len(buckets) {
+			return
+		}
+		buckets[i].Count++
+		for _, field := range bq.Sum {
+			value, _ = lookup(data, field)
+			switch number := normalize(value).(type) {
+			case int64:
+				buckets[i].Sums[field] += float64(number)
+			case float64:
+				buckets[i].Sums[field] += number
+			}
+		}
+	})
+}
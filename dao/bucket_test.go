@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketRange(tt *testing.T) {
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	buckets, err := bucketRange(BucketQuery{From: from, To: from.Add(150 * time.Minute), Interval: time.Hour})
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+	if len(buckets) != 3 {
+		tt.Fatalf("got %d buckets, want 3", len(buckets))
+	}
+	if !buckets[1].Start.Equal(from.Add(time.Hour)) || !buckets[2].End.Equal(from.Add(150*time.Minute)) {
+		tt.Errorf("got buckets %+v, want the last one cut at To", buckets)
+	}
+
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		tt.Skipf("no time zone data: %v", err)
+	}
+	// The clocks go forward on March 31, making that day 23 hours long.
+	start := time.Date(2024, 3, 30, 0, 0, 0, 0, stockholm)
+	buckets, err = bucketRange(BucketQuery{
+		From:     start,
+		To:       start.AddDate(0, 0, 3),
+		Interval: 24 * time.Hour,
+		Location: stockholm,
+	})
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+	if len(buckets) != 3 {
+		tt.Fatalf("got %d buckets, want 3", len(buckets))
+	}
+	for i, b := range buckets {
+		if b.Start.Hour() != 0 || b.End.Hour() != 0 {
+			tt.Errorf("bucket %d is not aligned to midnight: %s to %s", i, b.Start, b.End)
+		}
+	}
+	if d := buckets[1].End.Sub(buckets[1].Start); d != 23*time.Hour {
+		tt.Errorf("got a DST day of %s, want 23h", d)
+	}
+}
+
+func TestBucketRangeRejects(tt *testing.T) {
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rejected := map[string]BucketQuery{
+		"no interval":      {From: from, To: from.Add(time.Hour)},
+		"negative range":   {From: from, To: from.Add(-time.Hour), Interval: time.Minute},
+		"empty range":      {From: from, To: from, Interval: time.Minute},
+		"too many buckets": {From: from, To: from.Add(maxBuckets*time.Minute + time.Second), Interval: time.Minute},
+	}
+
+	for name, bq := range rejected {
+		_, err := bucketRange(bq)
+		if err == nil {
+			tt.Errorf("%s: expected an error", name)
+		}
+	}
+
+	_, err := bucketRange(BucketQuery{From: from, To: from.Add(maxBuckets * time.Minute), Interval: time.Minute})
+	if err != nil {
+		tt.Errorf("exactly %d buckets: unexpected error: %v", maxBuckets, err)
+	}
+}
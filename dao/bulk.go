@@ -0,0 +1,204 @@
+package dao
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Firestore's 500/50/5 rule: start at 500 operations per second and increase
+// traffic by 50% every 5 minutes.
+const (
+	bulkStartRate    = 500
+	bulkRampFactor   = 1.5
+	bulkRampInterval = 5 * time.Minute
+
+	bulkMaxAttempts = 5
+	bulkBackoff     = time.Second
+
+	// Throttling cuts the rate by bulkThrottleFactor, at most once per
+	// bulkBackoff, and never below bulkMinRate.
+	bulkThrottleFactor = 0.5
+	bulkMinRate        = 10
+)
+
+type BulkItem[Document any] struct {
+	ID       string
+	Document Document
+}
+
+type BulkOptions struct {
+	// Concurrency caps the number of writes in flight, defaults to 100.
+	Concurrency int
+	// ReportInterval between progress reports, defaults to 10 seconds.
+	ReportInterval time.Duration
+	// Progress is called with every report, in addition to it being logged.
+	Progress func(BulkProgress)
+	// OnError is called for every item that could not be created.
+	OnError func(id string, err error)
+}
+
+type BulkProgress struct {
+	Written int64
+	Failed  int64
+	Elapsed time.Duration
+	// Throughput in documents per second since the previous report.
+	Throughput float64
+	// Limit is the current ramp-up limit in operations per second.
+	Limit float64
+}
+
+// BulkCreate creates every item received until items is closed, ramping up
+// traffic according to Firestore's 500/50/5 rule. Writes failing with
+// ResourceExhausted are retried with backoff, cut the rate, and hold the
+// ramp-up back.
+func (c *DAO[Document]) BulkCreate(ctx context.Context, items <-chan BulkItem[Document], opts BulkOptions) (BulkProgress, error) {
+
+	log := c.log.Named("BulkCreate")
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 100
+	}
+	if opts.ReportInterval <= 0 {
+		opts.ReportInterval = 10 * time.Second
+	}
+
+	limiter := rate.NewLimiter(bulkStartRate, opts.Concurrency)
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	var written, failed atomic.Int64
+	var throttled atomic.Bool
+
+	var throttleMu sync.Mutex
+	var lastThrottle time.Time
+	throttle := func() {
+		throttled.Store(true)
+		throttleMu.Lock()
+		defer throttleMu.Unlock()
+		if time.Since(lastThrottle) < bulkBackoff {
+			return
+		}
+		lastThrottle = time.Now()
+		limit := limiter.Limit() * bulkThrottleFactor
+		if limit < bulkMinRate {
+			limit = bulkMinRate
+		}
+		limiter.SetLimit(limit)
+	}
+
+	ramp := time.NewTicker(bulkRampInterval)
+	defer ramp.Stop()
+	report := time.NewTicker(opts.ReportInterval)
+	defer report.Stop()
+
+	start := time.Now()
+	lastReport := start
+	var lastWritten int64
+
+	progress := func() BulkProgress {
+		now := time.Now()
+		p := BulkProgress{
+			Written: written.Load(),
+			Failed:  failed.Load(),
+			Elapsed: now.Sub(start),
+			Limit:   float64(limiter.Limit()),
+		}
+		if since := now.Sub(lastReport).Seconds(); since > 0 {
+			p.Throughput = float64(p.Written-lastWritten) / since
+		}
+		lastReport = now
+		lastWritten = p.Written
+		return p
+	}
+
+	var err error
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break loop
+
+		case <-ramp.C:
+			if throttled.Swap(false) {
+				continue
+			}
+			limiter.SetLimit(limiter.Limit() * bulkRampFactor)
+
+		case <-report.C:
+			p := progress()
+			log.With(
+				"collection", c.path,
+				"written", p.Written,
+				"failed", p.Failed,
+				"throughput", p.Throughput,
+				"limit", p.Limit,
+			).Info("bulk create progress")
+			if opts.Progress != nil {
+				opts.Progress(p)
+			}
+
+		case item, ok := <-items:
+			if !ok {
+				break loop
+			}
+			err = limiter.Wait(ctx)
+			if err != nil {
+				break loop
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				err := c.createWithBackoff(ctx, item, throttle)
+				if err != nil {
+					failed.Add(1)
+					if opts.OnError != nil {
+						opts.OnError(item.ID, err)
+					}
+					return
+				}
+				written.Add(1)
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	p := progress()
+	p.Throughput = float64(p.Written) / p.Elapsed.Seconds()
+	if opts.Progress != nil {
+		opts.Progress(p)
+	}
+
+	return p, err
+}
+
+func (c *DAO[Document]) createWithBackoff(ctx context.Context, item BulkItem[Document], throttle func()) error {
+
+	backoff := bulkBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.Create(ctx, item.ID, item.Document)
+		if status.Code(err) != codes.ResourceExhausted || attempt == bulkMaxAttempts {
+			return err
+		}
+		throttle()
+
+		jitter := time.Duration(rand.Int63n(int64(backoff)))
+		timer := time.NewTimer(backoff + jitter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,305 @@
+package dao
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	t "github.com/pergamenum/go-consensus-standards/types"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Bundle collects documents and named queries, possibly from several DAOs,
+// and writes them in the Firestore data bundle format that web and mobile
+// clients can load into their cache.
+type Bundle struct {
+	id      string
+	created time.Time
+	docs    map[string]*bundledDocument
+	order   []string
+	queries []bundledQuery
+}
+
+type bundledDocument struct {
+	data       map[string]any
+	createTime time.Time
+	updateTime time.Time
+	readTime   time.Time
+	queries    []string
+}
+
+type bundledQuery struct {
+	name     string
+	request  *pb.RunQueryRequest
+	readTime time.Time
+}
+
+func NewBundle(id string) *Bundle {
+
+	return &Bundle{
+		id:      id,
+		created: time.Now(),
+		docs:    map[string]*bundledDocument{},
+	}
+}
+
+// BundleDocument adds the document with the given ID to b.
+func (c *DAO[Document]) BundleDocument(ctx context.Context, b *Bundle, id string) error {
+
+	snapshot, err := c.c.Collection(c.path).Doc(id).Get(ctx)
+	if err != nil {
+		if !snapshot.Exists() {
+			cause := fmt.Sprintf("(ID: %s)", id)
+			return e.Wrap(cause, e.ErrNotFound)
+		}
+		return err
+	}
+
+	b.add(snapshot, "")
+
+	return nil
+}
+
+// BundleQuery adds the result of queries to b, under a name clients can use
+// to run the query from their cache.
+func (c *DAO[Document]) BundleQuery(ctx context.Context, b *Bundle, name string, queries []t.Query) error {
+
+	fsq, err := c.query(queries)
+	if err != nil {
+		return err
+	}
+
+	serialized, err := fsq.Serialize()
+	if err != nil {
+		cause := fmt.Sprintf("(query not supported: %s)", err.Error())
+		return e.Wrap(cause, e.ErrBadRequest)
+	}
+	var request pb.RunQueryRequest
+	err = proto.Unmarshal(serialized, &request)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := fsq.Documents(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+
+	readTime := time.Now()
+	for _, s := range snapshots {
+		b.add(s, name)
+		readTime = s.ReadTime
+	}
+
+	b.queries = append(b.queries, bundledQuery{
+		name:     name,
+		request:  &request,
+		readTime: readTime,
+	})
+
+	return nil
+}
+
+func (b *Bundle) add(s *firestore.DocumentSnapshot, query string) {
+
+	d, found := b.docs[s.Ref.Path]
+	if !found {
+		d = &bundledDocument{}
+		b.docs[s.Ref.Path] = d
+		b.order = append(b.order, s.Ref.Path)
+	}
+	d.data = s.Data()
+	d.createTime = s.CreateTime
+	d.updateTime = s.UpdateTime
+	d.readTime = s.ReadTime
+	if query != "" {
+		d.queries = append(d.queries, query)
+	}
+}
+
+// WriteTo writes b as a sequence of length-prefixed JSON elements, starting
+// with the bundle metadata.
+func (b *Bundle) WriteTo(w io.Writer) (int64, error) {
+
+	var body bytes.Buffer
+
+	for _, q := range b.queries {
+		structured, err := protojson.Marshal(q.request.GetStructuredQuery())
+		if err != nil {
+			return 0, err
+		}
+		err = writeElement(&body, map[string]any{
+			"namedQuery": map[string]any{
+				"name": q.name,
+				"bundledQuery": map[string]any{
+					"parent":          q.request.GetParent(),
+					"structuredQuery": json.RawMessage(structured),
+					"limitType":       "FIRST",
+				},
+				"readTime": bundleTime(q.readTime),
+			},
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, path := range b.order {
+		d := b.docs[path]
+		document, err := bundleDocument(path, d)
+		if err != nil {
+			return 0, err
+		}
+		metadata := map[string]any{
+			"name":     path,
+			"readTime": bundleTime(d.readTime),
+			"exists":   true,
+		}
+		if len(d.queries) > 0 {
+			metadata["queries"] = d.queries
+		}
+		err = writeElement(&body, map[string]any{
+			"documentMetadata": metadata,
+		})
+		if err != nil {
+			return 0, err
+		}
+		err = writeElement(&body, map[string]any{
+			"document": json.RawMessage(document),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var out bytes.Buffer
+	err := writeElement(&out, map[string]any{
+		"metadata": map[string]any{
+			"id":             b.id,
+			"createTime":     bundleTime(b.created),
+			"version":        1,
+			"totalDocuments": len(b.order),
+			"totalBytes":     body.Len(),
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	out.Write(body.Bytes())
+
+	return out.WriteTo(w)
+}
+
+// WriteResponse writes b as an HTTP response cacheable by browsers and CDNs
+// for maxAge.
+func (b *Bundle) WriteResponse(w http.ResponseWriter, maxAge time.Duration) error {
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+
+	_, err := b.WriteTo(w)
+
+	return err
+}
+
+func writeElement(w *bytes.Buffer, element any) error {
+
+	encoded, err := json.Marshal(element)
+	if err != nil {
+		return err
+	}
+	w.WriteString(strconv.Itoa(len(encoded)))
+	w.Write(encoded)
+
+	return nil
+}
+
+func bundleTime(ts time.Time) string {
+	return ts.UTC().Format(time.RFC3339Nano)
+}
+
+func bundleDocument(path string, d *bundledDocument) ([]byte, error) {
+
+	fields, err := bundleFields(d.data)
+	if err != nil {
+		cause := fmt.Sprintf("(bundling '%s' failed: %s)", path, err.Error())
+		return nil, e.Wrap(cause, e.ErrCorrupt)
+	}
+
+	return protojson.Marshal(&pb.Document{
+		Name:       path,
+		Fields:     fields,
+		CreateTime: timestamppb.New(d.createTime),
+		UpdateTime: timestamppb.New(d.updateTime),
+	})
+}
+
+func bundleFields(data map[string]any) (map[string]*pb.Value, error) {
+
+	fields := make(map[string]*pb.Value, len(data))
+	for key, value := range data {
+		v, err := bundleValue(value)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = v
+	}
+
+	return fields, nil
+}
+
+// bundleValue converts the values produced by DocumentSnapshot.Data back into
+// their protobuf representation.
+func bundleValue(value any) (*pb.Value, error) {
+
+	switch v := value.(type) {
+	case nil:
+		return &pb.Value{ValueType: &pb.Value_NullValue{NullValue: structpb.NullValue_NULL_VALUE}}, nil
+	case bool:
+		return &pb.Value{ValueType: &pb.Value_BooleanValue{BooleanValue: v}}, nil
+	case int64:
+		return &pb.Value{ValueType: &pb.Value_IntegerValue{IntegerValue: v}}, nil
+	case float64:
+		return &pb.Value{ValueType: &pb.Value_DoubleValue{DoubleValue: v}}, nil
+	case string:
+		return &pb.Value{ValueType: &pb.Value_StringValue{StringValue: v}}, nil
+	case []byte:
+		return &pb.Value{ValueType: &pb.Value_BytesValue{BytesValue: v}}, nil
+	case time.Time:
+		return &pb.Value{ValueType: &pb.Value_TimestampValue{TimestampValue: timestamppb.New(v)}}, nil
+	case *latlng.LatLng:
+		return &pb.Value{ValueType: &pb.Value_GeoPointValue{GeoPointValue: v}}, nil
+	case *firestore.DocumentRef:
+		return &pb.Value{ValueType: &pb.Value_ReferenceValue{ReferenceValue: v.Path}}, nil
+	case []any:
+		values := make([]*pb.Value, len(v))
+		for i, element := range v {
+			pv, err := bundleValue(element)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = pv
+		}
+		return &pb.Value{ValueType: &pb.Value_ArrayValue{ArrayValue: &pb.ArrayValue{Values: values}}}, nil
+	case map[string]any:
+		fields, err := bundleFields(v)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.Value{ValueType: &pb.Value_MapValue{MapValue: &pb.MapValue{Fields: fields}}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
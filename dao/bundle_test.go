@@ -0,0 +1,134 @@
+package dao
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+)
+
+func TestBundleWriteTo(tt *testing.T) {
+
+	root := "projects/p/databases/(default)/documents"
+	a, b := root+"/users/a", root+"/users/b"
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	read := created.Add(time.Second)
+
+	bundle := &Bundle{
+		id:      "jeff",
+		created: created,
+		docs: map[string]*bundledDocument{
+			a: {
+				data: map[string]any{
+					"n":      int64(1),
+					"tags":   []any{"x", true},
+					"nested": map[string]any{"f": 1.5},
+				},
+				createTime: created,
+				updateTime: created,
+				readTime:   read,
+				queries:    []string{"users"},
+			},
+			b: {
+				data: map[string]any{
+					"ref":  &firestore.DocumentRef{Path: a, ID: "a"},
+					"none": nil,
+				},
+				createTime: created,
+				updateTime: created,
+				readTime:   read,
+			},
+		},
+		order: []string{a, b},
+		queries: []bundledQuery{{
+			name: "users",
+			request: &pb.RunQueryRequest{
+				Parent: root,
+				QueryType: &pb.RunQueryRequest_StructuredQuery{StructuredQuery: &pb.StructuredQuery{
+					From: []*pb.StructuredQuery_CollectionSelector{{CollectionId: "users"}},
+				}},
+			},
+			readTime: read,
+		}},
+	}
+
+	var out bytes.Buffer
+	n, err := bundle.WriteTo(&out)
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+	if n != int64(out.Len()) {
+		tt.Errorf("got %d bytes written, want %d", n, out.Len())
+	}
+
+	// Every element is its length in decimal followed by exactly that many
+	// bytes of JSON.
+	stream := out.Bytes()
+	var elements [][]byte
+	var afterMetadata int
+	for len(stream) > 0 {
+		digits := 0
+		for digits < len(stream) && stream[digits] >= '0' && stream[digits] <= '9' {
+			digits++
+		}
+		size, err := strconv.Atoi(string(stream[:digits]))
+		if err != nil || digits+size > len(stream) {
+			tt.Fatalf("bad length prefix at %q", stream)
+		}
+		element := stream[digits : digits+size]
+		if !json.Valid(element) {
+			tt.Fatalf("element %q is not valid JSON", element)
+		}
+		elements = append(elements, element)
+		stream = stream[digits+size:]
+		if len(elements) == 1 {
+			afterMetadata = len(stream)
+		}
+	}
+
+	stamp := func(ts time.Time) string {
+		return ts.Format(time.RFC3339Nano)
+	}
+	golden := []string{
+		fmt.Sprintf(`{"metadata": {"id": "jeff", "createTime": %q, "version": 1, "totalDocuments": 2, "totalBytes": %d}}`,
+			stamp(created), afterMetadata),
+		fmt.Sprintf(`{"namedQuery": {"name": "users", "bundledQuery": {"parent": %q, "structuredQuery": {"from": [{"collectionId": "users"}]}, "limitType": "FIRST"}, "readTime": %q}}`,
+			root, stamp(read)),
+		fmt.Sprintf(`{"documentMetadata": {"name": %q, "readTime": %q, "exists": true, "queries": ["users"]}}`,
+			a, stamp(read)),
+		fmt.Sprintf(`{"document": {"name": %q, "fields": {
+			"n": {"integerValue": "1"},
+			"tags": {"arrayValue": {"values": [{"stringValue": "x"}, {"booleanValue": true}]}},
+			"nested": {"mapValue": {"fields": {"f": {"doubleValue": 1.5}}}}
+		}, "createTime": %q, "updateTime": %q}}`,
+			a, stamp(created), stamp(created)),
+		fmt.Sprintf(`{"documentMetadata": {"name": %q, "readTime": %q, "exists": true}}`,
+			b, stamp(read)),
+		fmt.Sprintf(`{"document": {"name": %q, "fields": {
+			"ref": {"referenceValue": %q},
+			"none": {"nullValue": null}
+		}, "createTime": %q, "updateTime": %q}}`,
+			b, a, stamp(created), stamp(created)),
+	}
+
+	if len(elements) != len(golden) {
+		tt.Fatalf("got %d elements, want %d", len(elements), len(golden))
+	}
+	for i, element := range elements {
+		var got, want any
+		_ = json.Unmarshal(element, &got)
+		err := json.Unmarshal([]byte(golden[i]), &want)
+		if err != nil {
+			tt.Fatalf("golden element %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			tt.Errorf("element %d: got %s, want %s", i, element, golden[i])
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+// coalesce shares one call of fn between all concurrent callers using the
+// same key. The shared call runs detached from the callers' contexts, so one
+// caller giving up does not fail the others, but bounded by the coalescing
+// timeout. All callers get the same value.
+func (c *DAO[Document]) coalesce(ctx context.Context, key string, fn func(context.Context) (any, error)) (any, error) {
+
+	ch := c.flight.DoChan(key, func() (any, error) {
+		shared, cancel := context.WithTimeout(detach(ctx), c.cfg.coalesceTimeout)
+		defer cancel()
+		return fn(shared)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		return r.Val, r.Err
+	}
+}
+
+func searchKey(queries []t.Query) string {
+
+	var sb strings.Builder
+	sb.WriteString("search")
+	for _, q := range queries {
+		fmt.Fprintf(&sb, "/%q %q %T %v", q.Key, strings.ToUpper(q.Operator), q.Value, q.Value)
+	}
+
+	return sb.String()
+}
+
+// detached keeps the values of a context but drops its deadline and
+// cancellation.
+type detached struct {
+	parent context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detached{parent: ctx}
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+func (d detached) Value(key any) any {
+	return d.parent.Value(key)
+}
@@ -0,0 +1,228 @@
+package dao
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+// Coalescer merges bursts of Update calls for the same document into a single
+// write per window. Later values for a field replace earlier ones, including
+// those of the fields under it, so transforms like firestore.Increment are not
+// accumulated. Writes for the same document never overlap, so that they land
+// in order.
+type Coalescer[Document any] struct {
+	ctx     context.Context
+	repo    Repository[Document]
+	window  time.Duration
+	onError func(id string, update t.Update, err error)
+
+	mu      sync.Mutex
+	pending map[string]t.Update
+	timers  map[string]*time.Timer
+	// flushing holds a channel per document with a write in flight, closed
+	// once the write is done.
+	flushing map[string]chan struct{}
+	closed   bool
+}
+
+// NewCoalescer flushes each document window after its first pending update,
+// writing with ctx. Failed flushes are reported to onError, which may be nil.
+func NewCoalescer[Document any](ctx context.Context, repo Repository[Document], window time.Duration, onError func(id string, update t.Update, err error)) *Coalescer[Document] {
+
+	return &Coalescer[Document]{
+		ctx:      ctx,
+		repo:     repo,
+		window:   window,
+		onError:  onError,
+		pending:  map[string]t.Update{},
+		timers:   map[string]*time.Timer{},
+		flushing: map[string]chan struct{}{},
+	}
+}
+
+// Update queues update for id and returns immediately. Once closed, the
+// update is written before returning instead.
+func (w *Coalescer[Document]) Update(ctx context.Context, id string, update t.Update) error {
+
+	w.mu.Lock()
+	merged, found := w.pending[id]
+	if !found {
+		merged = t.Update{}
+		w.pending[id] = merged
+		if !w.closed {
+			w.timers[id] = time.AfterFunc(w.window, func() {
+				w.flush(w.ctx, id)
+			})
+		}
+	}
+	for key, value := range update {
+		merge(merged, key, value)
+	}
+	closed := w.closed
+	w.mu.Unlock()
+
+	if closed {
+		return w.flush(ctx, id)
+	}
+
+	return nil
+}
+
+// Flush writes all pending updates now, and waits for writes already in
+// flight, returning the first error.
+func (w *Coalescer[Document]) Flush(ctx context.Context) error {
+
+	w.mu.Lock()
+	var ids []string
+	for id := range w.pending {
+		ids = append(ids, id)
+	}
+	w.mu.Unlock()
+
+	var first error
+	for _, id := range ids {
+		err := w.flush(ctx, id)
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+
+	w.mu.Lock()
+	var inFlight []chan struct{}
+	for _, done := range w.flushing {
+		inFlight = append(inFlight, done)
+	}
+	w.mu.Unlock()
+
+	for _, done := range inFlight {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if first == nil {
+				first = ctx.Err()
+			}
+			return first
+		}
+	}
+
+	return first
+}
+
+// Close flushes all pending updates and makes later updates write directly.
+func (w *Coalescer[Document]) Close(ctx context.Context) error {
+
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+
+	return w.Flush(ctx)
+}
+
+// flush writes the pending update of id, once the previous write of id is
+// done. If ctx ends first, the pending update is dropped and reported.
+func (w *Coalescer[Document]) flush(ctx context.Context, id string) error {
+
+	w.mu.Lock()
+	for {
+		done, busy := w.flushing[id]
+		if !busy {
+			break
+		}
+		w.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return w.drop(id, ctx.Err())
+		}
+		w.mu.Lock()
+	}
+
+	update, found := w.pending[id]
+	if !found {
+		w.mu.Unlock()
+		return nil
+	}
+	if timer, found := w.timers[id]; found {
+		timer.Stop()
+	}
+	delete(w.pending, id)
+	delete(w.timers, id)
+	done := make(chan struct{})
+	w.flushing[id] = done
+	w.mu.Unlock()
+
+	err := w.repo.Update(ctx, id, update)
+
+	w.mu.Lock()
+	delete(w.flushing, id)
+	close(done)
+	w.mu.Unlock()
+
+	if err != nil && w.onError != nil {
+		w.onError(id, update, err)
+	}
+
+	return err
+}
+
+// drop gives up on the pending update of id, reporting err for it.
+func (w *Coalescer[Document]) drop(id string, err error) error {
+
+	w.mu.Lock()
+	update, found := w.pending[id]
+	if timer, ok := w.timers[id]; ok {
+		timer.Stop()
+	}
+	delete(w.pending, id)
+	delete(w.timers, id)
+	w.mu.Unlock()
+
+	if found && w.onError != nil {
+		w.onError(id, update, err)
+	}
+
+	return err
+}
+
+// merge sets the field path key of pending to value the way a later write
+// would: pending fields under key are replaced, and a key under a pending
+// map is set within a copy of that map.
+func merge(pending t.Update, key string, value any) {
+
+	for path, v := range pending {
+		switch {
+		case strings.HasPrefix(path, key+"."):
+			delete(pending, path)
+		case strings.HasPrefix(key, path+"."):
+			m, ok := v.(map[string]any)
+			if !ok {
+				delete(pending, path)
+				continue
+			}
+			pending[path] = setPath(m, strings.Split(key[len(path)+1:], "."), value)
+			return
+		}
+	}
+	pending[key] = value
+}
+
+// setPath returns a copy of m with the nested field at path set to value.
+func setPath(m map[string]any, path []string, value any) map[string]any {
+
+	c := make(map[string]any, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	if len(path) == 1 {
+		c[path[0]] = value
+		return c
+	}
+	nested, _ := c[path[0]].(map[string]any)
+	c[path[0]] = setPath(nested, path[1:], value)
+
+	return c
+}
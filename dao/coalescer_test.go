@@ -0,0 +1,117 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+func TestCoalescerSerializesWrites(tt *testing.T) {
+
+	ctx := context.Background()
+	repo := &fakeRepository{gate: make(chan struct{})}
+	w := NewCoalescer[string](ctx, repo, time.Millisecond, nil)
+
+	_ = w.Update(ctx, "jeff", t.Update{"n": 1})
+	// Let the timer start the first write, which the gate holds.
+	time.Sleep(20 * time.Millisecond)
+	_ = w.Update(ctx, "jeff", t.Update{"n": 2})
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error)
+	go func() {
+		closed <- w.Close(ctx)
+	}()
+	repo.gate <- struct{}{}
+	repo.gate <- struct{}{}
+	err := <-closed
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.overlaps != 0 {
+		tt.Errorf("got %d overlapping writes", repo.overlaps)
+	}
+	if len(repo.updates) != 2 || repo.updates[0]["n"] != 1 || repo.updates[1]["n"] != 2 {
+		tt.Errorf("got updates %v, want n=1 then n=2", repo.updates)
+	}
+}
+
+func TestCoalescerReportsCancelledFlush(tt *testing.T) {
+
+	ctx := context.Background()
+	repo := &fakeRepository{gate: make(chan struct{})}
+	var mu sync.Mutex
+	var reported []t.Update
+	onError := func(id string, update t.Update, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !errors.Is(err, context.Canceled) {
+			tt.Errorf("got error %v, want context.Canceled", err)
+		}
+		reported = append(reported, update)
+	}
+	w := NewCoalescer[string](ctx, repo, time.Millisecond, onError)
+
+	_ = w.Update(ctx, "jeff", t.Update{"n": 1})
+	// Let the timer start the first write, which the gate holds.
+	time.Sleep(20 * time.Millisecond)
+	_ = w.Update(ctx, "jeff", t.Update{"n": 2})
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err := w.Flush(cancelled)
+	if !errors.Is(err, context.Canceled) {
+		tt.Errorf("got %v, want context.Canceled", err)
+	}
+	repo.gate <- struct{}{}
+
+	w.mu.Lock()
+	pending, timers := len(w.pending), len(w.timers)
+	w.mu.Unlock()
+	if pending != 0 || timers != 0 {
+		tt.Errorf("got %d pending updates and %d timers, want none", pending, timers)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 1 || reported[0]["n"] != 2 {
+		tt.Errorf("got reported %v, want n=2", reported)
+	}
+}
+
+func TestMerge(tt *testing.T) {
+
+	tests := []struct {
+		name    string
+		pending t.Update
+		key     string
+		value   any
+		want    t.Update
+	}{
+		{"same field", t.Update{"a": 1}, "a", 2, t.Update{"a": 2}},
+		{"other field", t.Update{"a": 1}, "b", 2, t.Update{"a": 1, "b": 2}},
+		{"sibling path", t.Update{"a.b": 1}, "a.c", 2, t.Update{"a.b": 1, "a.c": 2}},
+		{"replaces fields under it", t.Update{"a.b": 1, "a.c.d": 2, "ab": 3}, "a", 4, t.Update{"a": 4, "ab": 3}},
+		{"sets within a map", t.Update{"a": map[string]any{"b": 1, "c": 2}}, "a.b", 3,
+			t.Update{"a": map[string]any{"b": 3, "c": 2}}},
+		{"sets within nested maps", t.Update{"a": map[string]any{"b": map[string]any{"c": 1}}}, "a.b.d", 2,
+			t.Update{"a": map[string]any{"b": map[string]any{"c": 1, "d": 2}}}},
+		{"replaces a non-map", t.Update{"a": 1}, "a.b", 2, t.Update{"a.b": 2}},
+	}
+
+	for _, test := range tests {
+		tt.Run(test.name, func(tt *testing.T) {
+			merge(test.pending, test.key, test.value)
+			if !reflect.DeepEqual(test.pending, test.want) {
+				tt.Errorf("got %v, want %v", test.pending, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	t "github.com/pergamenum/go-consensus-standards/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (c *DAO[Document]) Exists(ctx context.Context, id string) (bool, error) {
+
+	_, err := c.c.Collection(c.path).Doc(id).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ReadOrCreate reads the document, or creates it from init if it does not
+// exist, in a single transaction.
+func (c *DAO[Document]) ReadOrCreate(ctx context.Context, id string, init func() Document) (Document, error) {
+
+	ref := c.c.Collection(c.path).Doc(id)
+
+	var d Document
+	err := c.c.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snapshot, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) != codes.NotFound {
+				return err
+			}
+			now := time.Now()
+			d = init()
+			if c.managed.tagged() {
+				data, err := c.createData(d, now)
+				if err != nil {
+					return err
+				}
+				err = tx.Create(ref, data)
+				if err != nil {
+					return err
+				}
+			} else {
+				err = tx.Create(ref, d)
+				if err != nil {
+					return err
+				}
+				err = tx.Update(ref, timestamps(now))
+				if err != nil {
+					return err
+				}
+			}
+			d, err = c.created(d, id, now)
+			return err
+		}
+
+		d, err = c.fromSnapshot(snapshot)
+
+		return err
+	})
+	if err != nil {
+		return c.empty, err
+	}
+
+	return d, nil
+}
+
+// UpdateIf applies update only if the current document matches all queries,
+// returning ErrConflict otherwise.
+func (c *DAO[Document]) UpdateIf(ctx context.Context, id string, queries []t.Query, update t.Update) error {
+
+	ref := c.c.Collection(c.path).Doc(id)
+
+	return c.c.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snapshot, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				cause := fmt.Sprintf("(ID: %s)", id)
+				return e.Wrap(cause, e.ErrNotFound)
+			}
+			return err
+		}
+
+		ok, err := matches(id, snapshot.Data(), queries)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			cause := fmt.Sprintf("(document '%s' does not match: %s)", id, queryShape(queries))
+			return e.Wrap(cause, e.ErrConflict)
+		}
+
+		fus := c.fromUpdate(update)
+		fus = append(fus, c.touched(time.Now())...)
+
+		return tx.Update(ref, fus)
+	})
+}
@@ -3,41 +3,83 @@ package dao
 import (
 	"context"
 	"fmt"
+	"math"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"cloud.google.com/go/firestore"
 	e "github.com/pergamenum/go-consensus-standards/ehandler"
 	t "github.com/pergamenum/go-consensus-standards/types"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type DAO[Document any] struct {
-	c     *firestore.Client
-	path  string
-	log   *zap.SugaredLogger
-	empty Document
+	c       *firestore.Client
+	path    string
+	log     *zap.SugaredLogger
+	slowLog *zap.SugaredLogger
+	cfg     config
+	flight  *singleflight.Group
+	managed managed
+	parseID *idParser
+	empty   Document
 }
 
-func NewDAO[Document any](fc *firestore.Client, path string, log *zap.SugaredLogger) *DAO[Document] {
+func NewDAO[Document any](fc *firestore.Client, path string, log *zap.SugaredLogger, opts ...Option) *DAO[Document] {
 
 	logNamed := log.Named("firestore.DAO")
+	cfg := newConfig(opts)
+
+	var flight *singleflight.Group
+	if cfg.coalesce {
+		flight = &singleflight.Group{}
+	}
 
 	return &DAO[Document]{
-		c:    fc,
-		path: path,
-		log:  logNamed,
+		c:       fc,
+		path:    path,
+		log:     logNamed,
+		slowLog: newSlowLogger(logNamed, cfg),
+		cfg:     cfg,
+		flight:  flight,
+		managed: managedFields(*new(Document)),
 	}
 }
 
 func (c *DAO[Document]) Create(ctx context.Context, id string, document Document) error {
 
 	now := time.Now()
+	// The ID may only be derived below.
+	defer func() { c.warnSlow(OpCreate, now, "id", id) }()
 
-	d := c.c.Collection(c.path).Doc(id)
-	_, err := d.Create(ctx, document)
+	if id == "" && c.cfg.key != nil {
+		var err error
+		id, err = c.KeyOf(document)
+		if err != nil {
+			return err
+		}
+	}
+
+	ref := c.c.Collection(c.path).Doc(id)
+	var err error
+	if c.managed.tagged() {
+		var data map[string]any
+		data, err = c.createData(document, now)
+		if err != nil {
+			return err
+		}
+		_, err = ref.Create(ctx, data)
+	} else {
+		// Firestore encodes the document, which is stamped in the same commit.
+		_, err = c.c.Batch().
+			Create(ref, document).
+			Update(ref, timestamps(now)).
+			Commit(ctx)
+	}
 	if err != nil {
 		if status.Code(err) == codes.AlreadyExists {
 			cause := fmt.Sprintf("(document '%s' already exists)", id)
@@ -46,26 +88,28 @@ func (c *DAO[Document]) Create(ctx context.Context, id string, document Document
 		return err
 	}
 
-	fus := []firestore.Update{
-		{
-			Path:  "created",
-			Value: now,
-		},
-		{
-			Path:  "updated",
-			Value: now,
-		},
+	return nil
+}
+
+func (c *DAO[Document]) Read(ctx context.Context, id string) (Document, error) {
+
+	defer c.warnSlow(OpRead, time.Now(), "id", id)
+
+	if c.flight == nil {
+		return c.read(ctx, id)
 	}
 
-	_, err = d.Update(ctx, fus)
+	v, err := c.coalesce(ctx, "read/"+id, func(ctx context.Context) (any, error) {
+		return c.read(ctx, id)
+	})
 	if err != nil {
-		return err
+		return c.empty, err
 	}
 
-	return nil
+	return v.(Document), nil
 }
 
-func (c *DAO[Document]) Read(ctx context.Context, id string) (Document, error) {
+func (c *DAO[Document]) read(ctx context.Context, id string) (Document, error) {
 
 	snapshot, err := c.c.Collection(c.path).Doc(id).Get(ctx)
 	if err != nil {
@@ -76,24 +120,16 @@ func (c *DAO[Document]) Read(ctx context.Context, id string) (Document, error) {
 		return c.empty, err
 	}
 
-	var d Document
-	err = snapshot.DataTo(&d)
-	if err != nil {
-		cause := fmt.Sprintf("(firestore serialization failed: %s)", err.Error())
-		return c.empty, e.Wrap(cause, e.ErrCorrupt)
-	}
-
-	return d, nil
+	return c.fromSnapshot(snapshot)
 }
 
 func (c *DAO[Document]) Update(ctx context.Context, id string, update t.Update) error {
 
+	defer c.warnSlow(OpUpdate, time.Now(), "id", id, "fields", len(update))
+
 	fus := c.fromUpdate(update)
 
-	fus = append(fus, firestore.Update{
-		Path:  "updated",
-		Value: time.Now(),
-	})
+	fus = append(fus, c.touched(time.Now())...)
 
 	_, err := c.c.Collection(c.path).Doc(id).Update(ctx, fus)
 	if err != nil {
@@ -105,6 +141,8 @@ func (c *DAO[Document]) Update(ctx context.Context, id string, update t.Update)
 
 func (c *DAO[Document]) Delete(ctx context.Context, id string) error {
 
+	defer c.warnSlow(OpDelete, time.Now(), "id", id)
+
 	_, err := c.c.Collection(c.path).Doc(id).Delete(ctx)
 	if err != nil {
 		return err
@@ -115,52 +153,247 @@ func (c *DAO[Document]) Delete(ctx context.Context, id string) error {
 
 func (c *DAO[Document]) Search(ctx context.Context, queries []t.Query) ([]Document, error) {
 
+	start := time.Now()
+	ds, err := c.coalescedSearch(ctx, queries)
+	c.warnSlow(OpSearch, start, "query", queryShape(queries), "results", len(ds))
+
+	return ds, err
+}
+
+func (c *DAO[Document]) coalescedSearch(ctx context.Context, queries []t.Query) ([]Document, error) {
+
+	if c.flight == nil {
+		return c.search(ctx, queries)
+	}
+
+	v, err := c.coalesce(ctx, searchKey(queries), func(ctx context.Context) (any, error) {
+		return c.search(ctx, queries)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Callers share the result, give each its own slice.
+	shared := v.([]Document)
+	ds := make([]Document, len(shared))
+	copy(ds, shared)
+
+	return ds, nil
+}
+
+func (c *DAO[Document]) search(ctx context.Context, queries []t.Query) ([]Document, error) {
+
 	log := c.log.Named("Search")
 
-	collection := c.c.Collection(c.path)
+	snapshots, err := c.snapshots(ctx, queries, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decode(log, snapshots), nil
+}
+
+// snapshots returns at most limit snapshots matching queries, zero meaning
+// no limit.
+func (c *DAO[Document]) snapshots(ctx context.Context, queries []t.Query, limit int) ([]*firestore.DocumentSnapshot, error) {
+
+	server, client, err := c.split(queries)
+	if err != nil {
+		return nil, err
+	}
+
 	var snapshots []*firestore.DocumentSnapshot
-	var err error
 	if len(queries) == 0 {
-		snapshots, err = collection.Documents(ctx).GetAll()
+		fsq := c.c.Collection(c.path).Query
+		if limit > 0 {
+			fsq = fsq.Limit(limit)
+		}
+		snapshots, err = fsq.Documents(ctx).GetAll()
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		var fsq firestore.Query
-		for i, q := range queries {
-			op := fro(q.Operator)
-			if i == 0 {
-				fsq = collection.Where(q.Key, op, q.Value)
-			} else {
-				fsq = fsq.Where(q.Key, op, q.Value)
-			}
-		}
-		snapshots, err = fsq.Documents(ctx).GetAll()
+		fsq, err := c.query(server)
 		if err != nil {
-			if status.Code(err) == codes.FailedPrecondition {
-				cause := "(query not supported: combining '==' with '!= <, <=, >, >=')"
-				return nil, e.Wrap(cause, e.ErrBadRequest)
-			}
 			return nil, err
 		}
+		if len(client) == 0 {
+			if limit > 0 {
+				fsq = fsq.Limit(limit)
+			}
+			snapshots, err = fsq.Documents(ctx).GetAll()
+			if err != nil {
+				return nil, queryError(err)
+			}
+		} else {
+			snapshots, err = c.filter(ctx, fsq, client)
+			if err != nil {
+				return nil, err
+			}
+			if limit > 0 && len(snapshots) > limit {
+				snapshots = snapshots[:limit]
+			}
+		}
 	}
 
+	return snapshots, nil
+}
+
+func (c *DAO[Document]) decode(log *zap.SugaredLogger, snapshots []*firestore.DocumentSnapshot) []Document {
+
 	var ds []Document
+	c.decodeEach(log, snapshots, func(_ *firestore.DocumentSnapshot, d Document) {
+		ds = append(ds, d)
+	})
+
+	return ds
+}
+
+// decodeEach calls fn with every snapshot that decodes.
+func (c *DAO[Document]) decodeEach(log *zap.SugaredLogger, snapshots []*firestore.DocumentSnapshot, fn func(*firestore.DocumentSnapshot, Document)) {
+
 	for _, s := range snapshots {
-		var d Document
-		err = s.DataTo(&d)
+		d, err := c.fromSnapshot(s)
 		if err != nil {
 			// Log corrupt snapshots as errors and then continue.
-			cause := fmt.Sprintf("(firestore serialization failed: %s)", err.Error())
-			wrapped := e.Wrap(cause, e.ErrCorrupt)
 			log.With("snapshot", s).
-				Error(wrapped)
+				Error(err)
 			continue
 		}
-		ds = append(ds, d)
+		fn(s, d)
 	}
+}
 
-	return ds, nil
+func queryError(err error) error {
+
+	if status.Code(err) == codes.FailedPrecondition {
+		cause := "(query not supported: combining '==' with '!= <, <=, >, >=')"
+		return e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	return err
+}
+
+func (c *DAO[Document]) query(queries []t.Query) (firestore.Query, error) {
+
+	err := validate(queries)
+	if err != nil {
+		return firestore.Query{}, err
+	}
+
+	fsq := c.c.Collection(c.path).Query
+	for _, q := range queries {
+		fsq = c.where(fsq, q)
+	}
+
+	return fsq, nil
+}
+
+// validate rejects queries Firestore would reject with a less helpful error.
+func validate(queries []t.Query) error {
+
+	var ranged string
+	for _, q := range queries {
+		op := strings.ToUpper(q.Operator)
+		if clientOperators[op] {
+			cause := fmt.Sprintf("(operator '%s' is only supported by Search)", q.Operator)
+			return e.Wrap(cause, e.ErrBadRequest)
+		}
+		switch op {
+		case "PREFIX":
+			if _, ok := q.Value.(string); !ok {
+				cause := fmt.Sprintf("(operator PREFIX needs a string, got %T)", q.Value)
+				return e.Wrap(cause, e.ErrBadRequest)
+			}
+			fallthrough
+		case "NE", "LT", "GT", "LE", "GE":
+			if ranged != "" && ranged != q.Key {
+				cause := fmt.Sprintf("(query not supported: inequality filters on both '%s' and '%s')", ranged, q.Key)
+				return e.Wrap(cause, e.ErrBadRequest)
+			}
+			ranged = q.Key
+		}
+	}
+
+	return nil
+}
+
+func (c *DAO[Document]) where(fsq firestore.Query, q t.Query) firestore.Query {
+
+	// Filters on the document ID compare document references.
+	value := q.Value
+	if q.Key == firestore.DocumentID {
+		value = c.idValue(value)
+	}
+
+	switch strings.ToUpper(q.Operator) {
+	case "ISNULL":
+		return fsq.Where(q.Key, "==", nil)
+	case "ISNAN":
+		return fsq.Where(q.Key, "==", math.NaN())
+	case "PREFIX":
+		prefix := q.Value.(string)
+		end, bounded := prefixEnd(prefix)
+		var lower, upper any = prefix, end
+		if q.Key == firestore.DocumentID {
+			lower, upper = c.idValue(lower), c.idValue(upper)
+		}
+		fsq = fsq.Where(q.Key, ">=", lower)
+		if !bounded {
+			return fsq
+		}
+		return fsq.Where(q.Key, "<", upper)
+	}
+
+	return fsq.Where(q.Key, fro(q.Operator), value)
+}
+
+// prefixEnd is the smallest string after every string starting with prefix,
+// so that [prefix, end) covers exactly those. Firestore orders strings by
+// their UTF-8 bytes, which is the order of their code points, so the last
+// code point that can be incremented is. There is no end when every code
+// point is the largest one.
+func prefixEnd(prefix string) (string, bool) {
+
+	for prefix != "" {
+		r, size := utf8.DecodeLastRuneInString(prefix)
+		prefix = prefix[:len(prefix)-size]
+		switch {
+		case r == utf8.MaxRune:
+			continue
+		case r == 0xD7FF:
+			// Skip the surrogates, which UTF-8 cannot encode.
+			return prefix + "\uE000", true
+		default:
+			return prefix + string(r+1), true
+		}
+	}
+
+	return "", false
+}
+
+func (c *DAO[Document]) idValue(value any) any {
+
+	collection := c.c.Collection(c.path)
+
+	switch v := value.(type) {
+	case string:
+		return collection.Doc(v)
+	case []string:
+		refs := make([]*firestore.DocumentRef, len(v))
+		for i, id := range v {
+			refs[i] = collection.Doc(id)
+		}
+		return refs
+	case []any:
+		refs := make([]any, len(v))
+		for i, id := range v {
+			refs[i] = c.idValue(id)
+		}
+		return refs
+	default:
+		return value
+	}
 }
 
 func (c *DAO[Document]) fromUpdate(input t.Update) []firestore.Update {
@@ -179,6 +412,20 @@ func (c *DAO[Document]) fromUpdate(input t.Update) []firestore.Update {
 	return fus
 }
 
+func timestamps(now time.Time) []firestore.Update {
+
+	return []firestore.Update{
+		{
+			Path:  "created",
+			Value: now,
+		},
+		{
+			Path:  "updated",
+			Value: now,
+		},
+	}
+}
+
 // fro = Firestore Relational Operator
 func fro(input string) string {
 	switch strings.ToUpper(input) {
@@ -194,6 +441,8 @@ func fro(input string) string {
 		return "<="
 	case "GE":
 		return ">="
+	case "IN":
+		return "in"
 	default:
 		return "UNKNOWN"
 	}
@@ -0,0 +1,41 @@
+package dao
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPrefixEnd(tt *testing.T) {
+
+	// U+F8FF was the old end of every prefix, U+D7FF is followed by the
+	// surrogates and U+1F600 is an emoji beyond both.
+	prefixes := []string{"a", "ab", "jeff", "\uf8ff", "é", "\U0001F600", "\ud7ff", "a" + string(utf8.MaxRune)}
+	candidates := []string{
+		"a", "ab", "abc", "b", "jeff", "jeff\uf8ff", "jeff\U0001F600", "jefg",
+		"\uf8ff", "\U0001F600", "\ufffd", "é", "éa", "ê", "\uf8ff\U0001F600",
+		"\U0001F600\U0001F600", "\U0001F601", "\ud7ff", "\ue000", "a" + string(utf8.MaxRune) + "z",
+	}
+
+	for _, prefix := range prefixes {
+		end, bounded := prefixEnd(prefix)
+		if !bounded {
+			tt.Errorf("'%s': expected an end", prefix)
+			continue
+		}
+		if !utf8.ValidString(end) {
+			tt.Errorf("'%s': end %q is not valid UTF-8", prefix, end)
+		}
+		for _, candidate := range candidates {
+			inRange := candidate >= prefix && candidate < end
+			if inRange != strings.HasPrefix(candidate, prefix) {
+				tt.Errorf("'%s' with end %q: '%s' in range is %v", prefix, end, candidate, inRange)
+			}
+		}
+	}
+
+	_, bounded := prefixEnd(string(utf8.MaxRune))
+	if bounded {
+		tt.Error("the largest code point should have no end")
+	}
+}
@@ -0,0 +1,254 @@
+package dao
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The struct encoding below follows the one of the pinned Firestore SDK
+// (v1.9.0, to_value.go and internal/fields), which is not exported, for the
+// top level of documents with managed fields. Nested values are still
+// encoded by the SDK.
+
+// storedField is a struct field as Firestore encodes it.
+type storedField struct {
+	name            string
+	tagged          bool
+	index           []int
+	omitEmpty       bool
+	serverTimestamp bool
+}
+
+// storedFields maps the Firestore names of the fields of v to their values,
+// leaving out managed fields.
+func storedFields(v reflect.Value) (map[string]any, error) {
+
+	fields, err := structFields(v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]any{}
+	for _, f := range fields {
+		sf := v.Type().FieldByIndex(f.index)
+		if isManaged(sf) {
+			continue
+		}
+		fv, err := v.FieldByIndexErr(f.index)
+		if err != nil {
+			// Promoted through a nil embedded pointer.
+			continue
+		}
+		if !fv.CanInterface() {
+			return nil, fmt.Errorf("field %s is promoted from an unexported embedded struct", sf.Name)
+		}
+
+		if f.serverTimestamp {
+			var zero bool
+			switch sf.Type {
+			case timeType:
+				zero = fv.Interface().(time.Time).IsZero()
+			case reflect.PointerTo(timeType):
+				zero = fv.IsNil() || fv.Elem().Interface().(time.Time).IsZero()
+			default:
+				return nil, fmt.Errorf("field %s with serverTimestamp tag must be of type time.Time or *time.Time", sf.Name)
+			}
+			// Firestore leaves out non-zero values.
+			if zero {
+				data[f.name] = firestore.ServerTimestamp
+			}
+			continue
+		}
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		data[f.name] = fv.Interface()
+	}
+
+	return data, nil
+}
+
+// structFields lists the fields Firestore encodes for t, promoting the fields
+// of untagged embedded structs, and dropping names that are ambiguous by Go's
+// rules.
+func structFields(t reflect.Type) ([]storedField, error) {
+
+	type scan struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var fields []storedField
+	visited := map[reflect.Type]bool{}
+	next := []scan{{typ: t}}
+	var nextCount map[reflect.Type]int
+
+	for len(next) > 0 {
+		current := next
+		next = nil
+		count := nextCount
+		nextCount = nil
+
+		for _, s := range current {
+			if visited[s.typ] {
+				continue
+			}
+			visited[s.typ] = true
+
+			for i := 0; i < s.typ.NumField(); i++ {
+				f := s.typ.Field(i)
+				if !f.IsExported() && !f.Anonymous {
+					continue
+				}
+				name, keep, opts, err := parseFirestoreTag(f.Tag)
+				if err != nil {
+					return nil, err
+				}
+				if !keep {
+					continue
+				}
+				index := append(s.index[:len(s.index):len(s.index)], i)
+
+				var embedded reflect.Type
+				if f.Anonymous && !isLeafType(f.Type) {
+					embedded = f.Type
+					if embedded.Kind() == reflect.Pointer {
+						embedded = embedded.Elem()
+					}
+				}
+				if name != "" || embedded == nil || embedded.Kind() != reflect.Struct {
+					if !f.IsExported() {
+						continue
+					}
+					field := storedField{
+						name:            name,
+						tagged:          name != "",
+						index:           index,
+						omitEmpty:       opts.omitEmpty,
+						serverTimestamp: opts.serverTimestamp,
+					}
+					if field.name == "" {
+						field.name = f.Name
+					}
+					fields = append(fields, field)
+					if count[s.typ] > 1 {
+						// Embedded more than once at this depth, so ambiguous.
+						fields = append(fields, field)
+					}
+					continue
+				}
+
+				if nextCount[embedded] > 0 {
+					// Embedded more than once at the next depth.
+					nextCount[embedded] = 2
+					continue
+				}
+				if nextCount == nil {
+					nextCount = map[reflect.Type]int{}
+				}
+				nextCount[embedded] = 1
+				if count[s.typ] > 1 {
+					nextCount[embedded] = 2
+				}
+				next = append(next, scan{typ: embedded, index: index})
+			}
+		}
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		a, b := fields[i], fields[j]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		if len(a.index) != len(b.index) {
+			return len(a.index) < len(b.index)
+		}
+		return a.tagged && !b.tagged
+	})
+
+	// The shallowest field of a name wins, preferring tagged ones, unless
+	// another is just as shallow and just as tagged.
+	var dominant []storedField
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		ambiguous := j-i > 1 &&
+			len(fields[i].index) == len(fields[i+1].index) &&
+			fields[i].tagged == fields[i+1].tagged
+		if !ambiguous {
+			dominant = append(dominant, fields[i])
+		}
+		i = j
+	}
+
+	return dominant, nil
+}
+
+type tagOptions struct {
+	omitEmpty       bool
+	serverTimestamp bool
+}
+
+func parseFirestoreTag(tag reflect.StructTag) (name string, keep bool, opts tagOptions, err error) {
+
+	parts := strings.Split(tag.Get("firestore"), ",")
+	if parts[0] == "-" {
+		if len(parts) > 1 {
+			return "", false, opts, fmt.Errorf(`"-" field tag with options`)
+		}
+		return "", false, opts, nil
+	}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "omitempty":
+			opts.omitEmpty = true
+		case "serverTimestamp":
+			opts.serverTimestamp = true
+		default:
+			return "", false, opts, fmt.Errorf("unknown tag option: %q", opt)
+		}
+	}
+
+	return parts[0], true, opts, nil
+}
+
+func isLeafType(t reflect.Type) bool {
+
+	return t == timeType ||
+		t == reflect.TypeOf((*latlng.LatLng)(nil)) ||
+		t == reflect.TypeOf((*timestamppb.Timestamp)(nil))
+}
+
+// isEmptyValue is what omitempty leaves out: like encoding/json, plus the
+// zero time.Time.
+func isEmptyValue(v reflect.Value) bool {
+
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+
+	return false
+}
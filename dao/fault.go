@@ -0,0 +1,154 @@
+package dao
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	t "github.com/pergamenum/go-consensus-standards/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Fault describes a misbehaviour to inject into a Repository call.
+type Fault struct {
+	// Operation limits the fault to one operation, empty matches all.
+	Operation Operation
+	// Probability in [0, 1] that the fault triggers on a matching call.
+	Probability float64
+	// Latency is added before the call, regardless of Code and Err.
+	Latency time.Duration
+	// Code is returned as a gRPC status error, as Firestore would.
+	Code codes.Code
+	// Err is returned instead of Code when set.
+	Err error
+	// Partial lets the call reach the wrapped Repository before the error is
+	// returned, simulating a write that was applied but reported as failed.
+	Partial bool
+}
+
+// FaultDAO wraps a Repository and injects faults, for resilience testing.
+type FaultDAO[Document any] struct {
+	next   Repository[Document]
+	faults []Fault
+	mu     sync.Mutex
+	rng    *rand.Rand
+}
+
+var _ Repository[any] = (*FaultDAO[any])(nil)
+
+// NewFaultDAO uses seed to make the sequence of injected faults deterministic.
+func NewFaultDAO[Document any](next Repository[Document], seed int64, faults ...Fault) *FaultDAO[Document] {
+
+	return &FaultDAO[Document]{
+		next:   next,
+		faults: faults,
+		rng:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (f *FaultDAO[Document]) Create(ctx context.Context, id string, document Document) error {
+
+	return f.inject(ctx, OpCreate, func() error {
+		return f.next.Create(ctx, id, document)
+	})
+}
+
+func (f *FaultDAO[Document]) Read(ctx context.Context, id string) (Document, error) {
+
+	var d Document
+	err := f.inject(ctx, OpRead, func() error {
+		var err error
+		d, err = f.next.Read(ctx, id)
+		return err
+	})
+	if err != nil {
+		var empty Document
+		return empty, err
+	}
+
+	return d, nil
+}
+
+func (f *FaultDAO[Document]) Update(ctx context.Context, id string, update t.Update) error {
+
+	return f.inject(ctx, OpUpdate, func() error {
+		return f.next.Update(ctx, id, update)
+	})
+}
+
+func (f *FaultDAO[Document]) Delete(ctx context.Context, id string) error {
+
+	return f.inject(ctx, OpDelete, func() error {
+		return f.next.Delete(ctx, id)
+	})
+}
+
+func (f *FaultDAO[Document]) Search(ctx context.Context, queries []t.Query) ([]Document, error) {
+
+	var ds []Document
+	err := f.inject(ctx, OpSearch, func() error {
+		var err error
+		ds, err = f.next.Search(ctx, queries)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+func (f *FaultDAO[Document]) inject(ctx context.Context, op Operation, call func() error) error {
+
+	fault, triggered := f.roll(op)
+	if !triggered {
+		return call()
+	}
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	injected := fault.Err
+	if injected == nil && fault.Code != codes.OK {
+		injected = status.Error(fault.Code, "injected fault")
+	}
+
+	if injected == nil {
+		return call()
+	}
+
+	if fault.Partial {
+		_ = call()
+	}
+
+	return injected
+}
+
+// roll picks the first matching fault that triggers. Matching faults consume
+// a random number each, up to the one that triggers, so the outcome only
+// depends on the seed and the order of calls.
+func (f *FaultDAO[Document]) roll(op Operation) (Fault, bool) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, fault := range f.faults {
+		if fault.Operation != "" && fault.Operation != op {
+			continue
+		}
+		if f.rng.Float64() < fault.Probability {
+			return fault, true
+		}
+	}
+
+	return Fault{}, false
+}
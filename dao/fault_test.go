@@ -0,0 +1,76 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func faultSequence(seed int64) []codes.Code {
+
+	ctx := context.Background()
+	f := NewFaultDAO[string](&fakeRepository{}, seed,
+		Fault{Operation: OpRead, Probability: 0.3, Code: codes.Unavailable},
+		Fault{Probability: 0.2, Code: codes.DeadlineExceeded},
+	)
+
+	var sequence []codes.Code
+	for i := 0; i < 100; i++ {
+		var err error
+		if i%2 == 0 {
+			_, err = f.Read(ctx, "jeff")
+		} else {
+			err = f.Delete(ctx, "jeff")
+		}
+		sequence = append(sequence, status.Code(err))
+	}
+
+	return sequence
+}
+
+func TestFaultDAODeterministic(tt *testing.T) {
+
+	first := faultSequence(42)
+	if !reflect.DeepEqual(first, faultSequence(42)) {
+		tt.Error("the same seed gave different fault sequences")
+	}
+	if reflect.DeepEqual(first, faultSequence(43)) {
+		tt.Error("different seeds gave the same fault sequence")
+	}
+
+	faults := map[codes.Code]int{}
+	for i, code := range first {
+		faults[code]++
+		if i%2 == 1 && code == codes.Unavailable {
+			tt.Errorf("call %d: a Read fault was injected into Delete", i)
+		}
+	}
+	if faults[codes.Unavailable] == 0 || faults[codes.DeadlineExceeded] == 0 || faults[codes.OK] == 0 {
+		tt.Errorf("got fault counts %v, want every outcome", faults)
+	}
+}
+
+func TestFaultDAOPartial(tt *testing.T) {
+
+	ctx := context.Background()
+	injected := errors.New("injected")
+	repo := &fakeRepository{}
+	f := NewFaultDAO[string](repo, 1, Fault{Operation: OpCreate, Probability: 1, Err: injected, Partial: true})
+
+	err := f.Create(ctx, "jeff", "jeff")
+	if !errors.Is(err, injected) {
+		tt.Errorf("got %v, want the injected error", err)
+	}
+	if len(repo.calls) != 1 || repo.calls[0] != OpCreate {
+		tt.Errorf("got calls %v, want the create to reach the repository", repo.calls)
+	}
+
+	err = f.Delete(ctx, "jeff")
+	if err != nil {
+		tt.Errorf("got %v, want no fault for Delete", err)
+	}
+}
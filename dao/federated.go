@@ -0,0 +1,97 @@
+package dao
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+// Source is a collection taking part in a FederatedSearch.
+type Source struct {
+	collection string
+	search     func(ctx context.Context, queries []t.Query) ([]Hit, error)
+}
+
+type Hit struct {
+	Collection string `json:"collection"`
+	ID         string `json:"id"`
+	Document   any    `json:"document"`
+}
+
+type FederatedResult struct {
+	Hits []Hit `json:"hits"`
+	// Failures describes the error of every source that failed, by
+	// collection.
+	Failures map[string]string `json:"failures,omitempty"`
+	// Errors holds the same errors, for errors.Is and errors.As.
+	Errors map[string]error `json:"-"`
+}
+
+// AsSource lets dao take part in a FederatedSearch, returning at most limit
+// documents, where zero means no limit.
+func AsSource[Document any](dao *DAO[Document], limit int) Source {
+
+	return Source{
+		collection: dao.path,
+		search: func(ctx context.Context, queries []t.Query) ([]Hit, error) {
+			return dao.hits(ctx, queries, limit)
+		},
+	}
+}
+
+// FederatedSearch runs queries against all sources concurrently. Sources that
+// fail are reported in the result, next to the hits of the others.
+func FederatedSearch(ctx context.Context, queries []t.Query, sources ...Source) FederatedResult {
+
+	results := make([][]Hit, len(sources))
+	errs := make([]error, len(sources))
+
+	var wg sync.WaitGroup
+	for i, s := range sources {
+		i, s := i, s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.search(ctx, queries)
+		}()
+	}
+	wg.Wait()
+
+	result := FederatedResult{
+		Failures: map[string]string{},
+		Errors:   map[string]error{},
+	}
+	for i, s := range sources {
+		if errs[i] != nil {
+			result.Failures[s.collection] = errs[i].Error()
+			result.Errors[s.collection] = errs[i]
+			continue
+		}
+		result.Hits = append(result.Hits, results[i]...)
+	}
+
+	return result
+}
+
+func (c *DAO[Document]) hits(ctx context.Context, queries []t.Query, limit int) ([]Hit, error) {
+
+	log := c.log.Named("FederatedSearch")
+
+	snapshots, err := c.snapshots(ctx, queries, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []Hit
+	c.decodeEach(log, snapshots, func(s *firestore.DocumentSnapshot, d Document) {
+		hits = append(hits, Hit{
+			Collection: c.path,
+			ID:         s.Ref.ID,
+			Document:   d,
+		})
+	})
+
+	return hits, nil
+}
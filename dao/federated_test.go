@@ -0,0 +1,43 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+func TestFederatedSearchReportsFailures(tt *testing.T) {
+
+	failure := errors.New("index missing")
+	sources := []Source{
+		{
+			collection: "users",
+			search: func(ctx context.Context, queries []t.Query) ([]Hit, error) {
+				return []Hit{{Collection: "users", ID: "jeff"}}, nil
+			},
+		},
+		{
+			collection: "orders",
+			search: func(ctx context.Context, queries []t.Query) ([]Hit, error) {
+				return nil, failure
+			},
+		},
+	}
+
+	result := FederatedSearch(context.Background(), nil, sources...)
+	if len(result.Hits) != 1 || !errors.Is(result.Errors["orders"], failure) {
+		tt.Fatalf("got %+v", result)
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(encoded), `"failures":{"orders":"index missing"}`) {
+		tt.Errorf("failures missing from %s", encoded)
+	}
+}
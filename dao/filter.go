@@ -0,0 +1,173 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	t "github.com/pergamenum/go-consensus-standards/types"
+	"google.golang.org/api/iterator"
+)
+
+// Firestore never matches a document on a field it does not have, not even
+// with NE or NOTNULL. ISNULL and ISNAN are sent to Firestore, while the
+// operators below are evaluated client-side, on every document matching the
+// remaining queries, which Firestore bills as reads. They are therefore only
+// accepted by Search when the DAO is created WithClientFilters. EXISTS and
+// MISSING have no Firestore filter. NOTNULL does, IS_NOT_NULL, but the Go SDK
+// (v1.9.0) rejects the "!= nil" it would be built from.
+//
+//	NOTNULL  the field is set to anything but null
+//	EXISTS   the field is set, to anything including null
+//	MISSING  the field is not set
+var clientOperators = map[string]bool{
+	"NOTNULL": true,
+	"EXISTS":  true,
+	"MISSING": true,
+}
+
+// split separates the queries Firestore can run from the ones evaluated
+// client-side.
+func (c *DAO[Document]) split(queries []t.Query) (server, client []t.Query, err error) {
+
+	for _, q := range queries {
+		if !clientOperators[strings.ToUpper(q.Operator)] {
+			server = append(server, q)
+			continue
+		}
+		if !c.cfg.clientFilters {
+			cause := fmt.Sprintf("(operator '%s' needs client-side filtering, which is disabled)", q.Operator)
+			return nil, nil, e.Wrap(cause, e.ErrBadRequest)
+		}
+		client = append(client, q)
+	}
+
+	if c.cfg.planner {
+		var remaining []t.Query
+		server, remaining = plan(server)
+		client = append(client, remaining...)
+	}
+
+	return server, client, nil
+}
+
+// plan pushes down all equality filters, but only the inequality filters on
+// the most selective field, since Firestore only supports inequality filters
+// on a single field. A field bounded from both sides is considered more
+// selective than a field bounded from one side, which in turn is more
+// selective than a NE filter.
+func plan(queries []t.Query) (server, client []t.Query) {
+
+	const (
+		lower = 1 << iota
+		upper
+		notEqual
+	)
+
+	bounds := map[string]int{}
+	var fields []string
+	for _, q := range queries {
+		var bound int
+		switch strings.ToUpper(q.Operator) {
+		case "GT", "GE":
+			bound = lower
+		case "LT", "LE":
+			bound = upper
+		case "PREFIX":
+			bound = lower | upper
+		case "NE":
+			bound = notEqual
+		default:
+			continue
+		}
+		if _, found := bounds[q.Key]; !found {
+			fields = append(fields, q.Key)
+		}
+		bounds[q.Key] |= bound
+	}
+
+	selectivity := func(bound int) int {
+		switch {
+		case bound&lower != 0 && bound&upper != 0:
+			return 3
+		case bound&(lower|upper) != 0:
+			return 2
+		default:
+			return 1
+		}
+	}
+
+	var best string
+	for _, field := range fields {
+		if best == "" || selectivity(bounds[field]) > selectivity(bounds[best]) {
+			best = field
+		}
+	}
+
+	for _, q := range queries {
+		if q.Key != best && inequality(q.Operator) {
+			client = append(client, q)
+			continue
+		}
+		server = append(server, q)
+	}
+
+	return server, client
+}
+
+func inequality(op string) bool {
+
+	switch strings.ToUpper(op) {
+	case "NE", "LT", "GT", "LE", "GE", "PREFIX":
+		return true
+	default:
+		return false
+	}
+}
+
+// filter streams the result of fsq, keeping the snapshots matching client.
+func (c *DAO[Document]) filter(ctx context.Context, fsq firestore.Query, client []t.Query) ([]*firestore.DocumentSnapshot, error) {
+
+	var snapshots []*firestore.DocumentSnapshot
+	err := c.scan(ctx, fsq, client, c.cfg.maxScanned, func(s *firestore.DocumentSnapshot) {
+		snapshots = append(snapshots, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// scan streams the result of fsq, calling fn with every snapshot matching
+// client, and fails once more than maxScanned documents are scanned, unless
+// maxScanned is zero.
+func (c *DAO[Document]) scan(ctx context.Context, fsq firestore.Query, client []t.Query, maxScanned int, fn func(*firestore.DocumentSnapshot)) error {
+
+	it := fsq.Documents(ctx)
+	defer it.Stop()
+
+	for scanned := 1; ; scanned++ {
+		s, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return queryError(err)
+		}
+		if maxScanned > 0 && scanned > maxScanned {
+			cause := fmt.Sprintf("(query scans more than %d documents, narrow it down)", maxScanned)
+			return e.Wrap(cause, e.ErrBadRequest)
+		}
+
+		ok, err := matches(s.Ref.ID, s.Data(), client)
+		if err != nil {
+			return err
+		}
+		if ok {
+			fn(s)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"reflect"
+	"testing"
+
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+func TestPlan(tt *testing.T) {
+
+	eq := t.Query{Key: "tenant", Operator: "EQ", Value: "acme"}
+	ageGT := t.Query{Key: "age", Operator: "GT", Value: 18}
+	ageLT := t.Query{Key: "age", Operator: "lt", Value: 65}
+	nameNE := t.Query{Key: "name", Operator: "NE", Value: "jeff"}
+	namePrefix := t.Query{Key: "name", Operator: "PREFIX", Value: "j"}
+	scoreGE := t.Query{Key: "score", Operator: "GE", Value: 1}
+
+	tests := []struct {
+		name           string
+		queries        []t.Query
+		server, client []t.Query
+	}{
+		{"equality only", []t.Query{eq}, []t.Query{eq}, nil},
+		{"single inequality", []t.Query{eq, ageGT}, []t.Query{eq, ageGT}, nil},
+		{"range beats NE", []t.Query{nameNE, ageGT, eq}, []t.Query{ageGT, eq}, []t.Query{nameNE}},
+		{"both bounds beat one", []t.Query{scoreGE, ageGT, ageLT}, []t.Query{ageGT, ageLT}, []t.Query{scoreGE}},
+		{"prefix bounds both", []t.Query{ageGT, namePrefix}, []t.Query{namePrefix}, []t.Query{ageGT}},
+		{"first field wins a tie", []t.Query{scoreGE, ageGT}, []t.Query{scoreGE}, []t.Query{ageGT}},
+	}
+
+	for _, test := range tests {
+		tt.Run(test.name, func(tt *testing.T) {
+			server, client := plan(test.queries)
+			if !reflect.DeepEqual(server, test.server) || !reflect.DeepEqual(client, test.client) {
+				tt.Errorf("got server %v and client %v, want %v and %v", server, client, test.server, test.client)
+			}
+		})
+	}
+}
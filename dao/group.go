@@ -0,0 +1,152 @@
+package dao
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	t "github.com/pergamenum/go-consensus-standards/types"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// defaultMaxScanned caps Distinct and GroupCount when the DAO has no limit
+// of its own, as they read every matching document.
+const defaultMaxScanned = 10000
+
+type Group struct {
+	Value any `json:"value"`
+	Count int `json:"count"`
+}
+
+// Distinct returns the distinct values of field among the documents matching
+// queries, in Firestore's order. Documents without the field are skipped.
+func (c *DAO[Document]) Distinct(ctx context.Context, field string, queries []t.Query) ([]any, error) {
+
+	groups, err := c.GroupCount(ctx, field, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(groups))
+	for i, g := range groups {
+		values[i] = g.Value
+	}
+
+	return values, nil
+}
+
+// GroupCount counts the documents matching queries per value of field, in
+// Firestore's order of the values. Only field, and the fields needed by
+// client-side filters, are read.
+func (c *DAO[Document]) GroupCount(ctx context.Context, field string, queries []t.Query) ([]Group, error) {
+
+	server, client, err := c.split(queries)
+	if err != nil {
+		return nil, err
+	}
+	fsq, err := c.query(server)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{field}
+	for _, q := range client {
+		if q.Key != firestore.DocumentID {
+			paths = append(paths, q.Key)
+		}
+	}
+	fsq = fsq.Select(paths...)
+
+	maxScanned := c.cfg.maxScanned
+	if maxScanned == 0 {
+		maxScanned = defaultMaxScanned
+	}
+
+	groups := map[string]*Group{}
+	err = c.scan(ctx, fsq, client, maxScanned, func(s *firestore.DocumentSnapshot) {
+		value, found := lookup(s.Data(), field)
+		if !found {
+			return
+		}
+		key := groupKey(value)
+		g, found := groups[key]
+		if !found {
+			g = &Group{Value: value}
+			groups[key] = g
+		}
+		g.Count++
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Group
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return sortOrder(result[i].Value, result[j].Value) < 0
+	})
+
+	return result, nil
+}
+
+// groupKey encodes value canonically, so that values Firestore considers
+// equal, like references to the same document or 1 and 1.0, share a key.
+func groupKey(value any) string {
+
+	switch v := normalize(value).(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "b:" + strconv.FormatBool(v)
+	case int64:
+		return "n:" + strconv.FormatInt(v, 10)
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return "n:" + strconv.FormatInt(int64(v), 10)
+		}
+		return "n:" + strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return "t:" + v.UTC().Format(time.RFC3339Nano)
+	case string:
+		return "s:" + strconv.Quote(v)
+	case []byte:
+		return "y:" + base64.StdEncoding.EncodeToString(v)
+	case *firestore.DocumentRef:
+		if v == nil {
+			return "null"
+		}
+		return "r:" + strconv.Quote(v.Path)
+	case *latlng.LatLng:
+		if v == nil {
+			return "null"
+		}
+		return fmt.Sprintf("g:%v,%v", v.Latitude, v.Longitude)
+	case []any:
+		keys := make([]string, len(v))
+		for i, element := range v {
+			keys[i] = groupKey(element)
+		}
+		return "a:[" + strings.Join(keys, ",") + "]"
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		entries := make([]string, len(names))
+		for i, name := range names {
+			entries[i] = strconv.Quote(name) + ":" + groupKey(v[name])
+		}
+		return "m:{" + strings.Join(entries, ",") + "}"
+	default:
+		return fmt.Sprintf("%T:%v", v, v)
+	}
+}
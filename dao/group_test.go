@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+func TestGroupKey(tt *testing.T) {
+
+	path := "projects/p/databases/(default)/documents/users/jeff"
+	ref := func() *firestore.DocumentRef {
+		return &firestore.DocumentRef{
+			Parent: &firestore.CollectionRef{ID: "users"},
+			Path:   path,
+			ID:     "jeff",
+		}
+	}
+
+	same := [][2]any{
+		{ref(), ref()},
+		{int64(1), 1.0},
+		{[]any{ref(), "a"}, []any{ref(), "a"}},
+		{map[string]any{"a": 1, "b": ref()}, map[string]any{"b": ref(), "a": int64(1)}},
+	}
+	for _, values := range same {
+		if groupKey(values[0]) != groupKey(values[1]) {
+			tt.Errorf("%v and %v should share a key", values[0], values[1])
+		}
+	}
+
+	different := [][2]any{
+		{"1", 1},
+		{1.5, 1},
+		{[]any{"a,b"}, []any{"a", "b"}},
+		{map[string]any{"a": 1}, map[string]any{"a": 2}},
+		{nil, "null"},
+	}
+	for _, values := range different {
+		if groupKey(values[0]) == groupKey(values[1]) {
+			tt.Errorf("%v and %v should have different keys", values[0], values[1])
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package dao
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// reserved matches the IDs Firestore reserves for itself.
+var reserved = regexp.MustCompile(`^__.*__$`)
+
+// maxSegmentBytes is Firestore's limit on collection and document IDs.
+const maxSegmentBytes = 1500
+
+// idProblem describes why Firestore would reject id as a collection or
+// document ID, or is empty if it would not.
+func idProblem(id string) string {
+
+	switch {
+	case id == "":
+		return "is empty"
+	case id == "." || id == "..":
+		return "is not a valid ID"
+	case reserved.MatchString(id):
+		return "is reserved by Firestore"
+	case len(id) > maxSegmentBytes:
+		return fmt.Sprintf("is longer than %d bytes", maxSegmentBytes)
+	default:
+		return ""
+	}
+}
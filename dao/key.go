@@ -0,0 +1,176 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+)
+
+// Key derives document IDs from Document fields, like {tenant}_{date}_{sku}.
+// Components are percent-escaped where they contain the separator, '%' or
+// '/', or where they could make the ID match Firestore's reserved __.*__.
+type Key struct {
+	// Fields are the names of the Document struct fields, in ID order.
+	Fields []string
+	// Separator defaults to "_". It cannot contain '/', which separates path
+	// segments, nor '%' or hex digits, which would split escapes apart.
+	Separator string
+}
+
+// WithKey lets Create derive the document ID from key when given an empty ID,
+// and enables KeyOf, ParseKey and ReadByKey.
+func WithKey(key Key) Option {
+	return func(cfg *config) {
+		if key.Separator == "" {
+			key.Separator = "_"
+		}
+		cfg.key = &key
+	}
+}
+
+// KeyOf builds the document ID of document.
+func (c *DAO[Document]) KeyOf(document Document) (string, error) {
+
+	key, err := c.key()
+	if err != nil {
+		return "", err
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(document))
+	if v.Kind() != reflect.Struct {
+		cause := fmt.Sprintf("(keys need a struct document, got %T)", document)
+		return "", e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	components := make([]string, len(key.Fields))
+	for i, name := range key.Fields {
+		f, found := v.Type().FieldByName(name)
+		if !found {
+			cause := fmt.Sprintf("(key field '%s' not found in %T)", name, document)
+			return "", e.Wrap(cause, e.ErrBadRequest)
+		}
+		field, err := v.FieldByIndexErr(f.Index)
+		if err != nil || !f.IsExported() || !field.CanInterface() {
+			cause := fmt.Sprintf("(key field '%s' of %T is not exported)", name, document)
+			return "", e.Wrap(cause, e.ErrBadRequest)
+		}
+		components[i] = keyComponent(field.Interface())
+	}
+
+	return joinKey(key.Separator, components)
+}
+
+// ParseKey splits a document ID back into its unescaped components.
+func (c *DAO[Document]) ParseKey(id string) ([]string, error) {
+
+	key, err := c.key()
+	if err != nil {
+		return nil, err
+	}
+
+	escaped := strings.Split(id, key.Separator)
+	if len(escaped) != len(key.Fields) {
+		cause := fmt.Sprintf("(ID '%s' has %d components, expected %d)", id, len(escaped), len(key.Fields))
+		return nil, e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	components := make([]string, len(escaped))
+	for i, component := range escaped {
+		components[i], err = url.PathUnescape(component)
+		if err != nil {
+			cause := fmt.Sprintf("(ID '%s' is badly escaped: %s)", id, err.Error())
+			return nil, e.Wrap(cause, e.ErrBadRequest)
+		}
+	}
+
+	return components, nil
+}
+
+// ReadByKey reads the document whose ID is made of components, given in the
+// order of the key fields.
+func (c *DAO[Document]) ReadByKey(ctx context.Context, components ...string) (Document, error) {
+
+	key, err := c.key()
+	if err != nil {
+		return c.empty, err
+	}
+	if len(components) != len(key.Fields) {
+		cause := fmt.Sprintf("(got %d key components, expected %d)", len(components), len(key.Fields))
+		return c.empty, e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	id, err := joinKey(key.Separator, components)
+	if err != nil {
+		return c.empty, err
+	}
+
+	return c.Read(ctx, id)
+}
+
+func (c *DAO[Document]) key() (*Key, error) {
+
+	if c.cfg.key == nil {
+		cause := fmt.Sprintf("(no key defined for '%s')", c.path)
+		return nil, e.Wrap(cause, e.ErrBadRequest)
+	}
+	if strings.ContainsAny(c.cfg.key.Separator, "/%0123456789ABCDEFabcdef") {
+		cause := fmt.Sprintf("(key separator '%s' of '%s' cannot contain '/', '%%' or hex digits)", c.cfg.key.Separator, c.path)
+		return nil, e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	return c.cfg.key, nil
+}
+
+func keyComponent(value any) string {
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// joinKey escapes and joins components, rejecting IDs that Firestore would
+// reject even though every component is escaped, like "__a__" joined from
+// "", "", "a", "" and "".
+func joinKey(separator string, components []string) (string, error) {
+
+	escaped := make([]string, len(components))
+	for i, component := range components {
+		escaped[i] = escapeComponent(component, separator)
+	}
+
+	id := strings.Join(escaped, separator)
+	if problem := idProblem(id); problem != "" {
+		cause := fmt.Sprintf("(key ID '%s' %s)", id, problem)
+		return "", e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	return id, nil
+}
+
+func escapeComponent(component, separator string) string {
+
+	var sb strings.Builder
+	for i := 0; i < len(component); i++ {
+		b := component[i]
+		reserved := b == '%' || b == '/' || strings.IndexByte(separator, b) >= 0 ||
+			(b == '_' && (i == 0 || i == len(component)-1)) ||
+			(b == '.' && (component == "." || component == ".."))
+		if reserved {
+			fmt.Fprintf(&sb, "%%%02X", b)
+			continue
+		}
+		sb.WriteByte(b)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,91 @@
+package dao
+
+import (
+	"reflect"
+	"testing"
+)
+
+type keyed struct {
+	Tenant string
+	Day    int
+	sku    string
+}
+
+func TestKeyRoundTrip(tt *testing.T) {
+
+	tests := []struct {
+		separator  string
+		components []string
+	}{
+		{"_", []string{"acme", "2024-01-01", "sku"}},
+		{"_", []string{"_acme_", "a_b", "c"}},
+		{"_", []string{"a/b", "50%", "."}},
+		{"_", []string{"..", "", "x"}},
+		{"_", []string{"ünïcode", "emoji 😀", "__"}},
+		{"::", []string{"a:b", "c::d", ":"}},
+	}
+
+	for _, test := range tests {
+		c := &DAO[keyed]{cfg: config{key: &Key{
+			Fields:    make([]string, len(test.components)),
+			Separator: test.separator,
+		}}}
+		id, err := joinKey(test.separator, test.components)
+		if err != nil {
+			tt.Errorf("%q: unexpected error: %v", test.components, err)
+			continue
+		}
+		parsed, err := c.ParseKey(id)
+		if err != nil {
+			tt.Errorf("%q: ID '%s' does not parse: %v", test.components, id, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, test.components) {
+			tt.Errorf("ID '%s' parses to %q, want %q", id, parsed, test.components)
+		}
+	}
+
+	// "a/b" escapes to "a%2Fb", which the separator "F" would split.
+	for _, separator := range []string{"F", "/", "%", "-1-"} {
+		c := &DAO[keyed]{cfg: config{key: &Key{Fields: []string{"Tenant"}, Separator: separator}}}
+		_, err := c.ParseKey("a%2Fb")
+		if err == nil {
+			tt.Errorf("separator '%s': expected an error", separator)
+		}
+		_, err = c.KeyOf(keyed{Tenant: "a/b"})
+		if err == nil {
+			tt.Errorf("separator '%s': expected an error", separator)
+		}
+	}
+}
+
+func TestJoinKeyRejects(tt *testing.T) {
+
+	rejected := [][]string{
+		{"", "", "a", "", ""},
+		{""},
+		{"", "", "", "", ""},
+	}
+
+	for _, components := range rejected {
+		id, err := joinKey("_", components)
+		if err == nil {
+			tt.Errorf("%q: expected an error, got ID '%s'", components, id)
+		}
+	}
+}
+
+func TestKeyOf(tt *testing.T) {
+
+	c := &DAO[keyed]{cfg: config{key: &Key{Fields: []string{"Tenant", "Day"}, Separator: "_"}}}
+	id, err := c.KeyOf(keyed{Tenant: "acme", Day: 7})
+	if err != nil || id != "acme_7" {
+		tt.Errorf("got ('%s', %v), want 'acme_7'", id, err)
+	}
+
+	c.cfg.key.Fields = []string{"Tenant", "sku"}
+	_, err = c.KeyOf(keyed{Tenant: "acme", sku: "s"})
+	if err == nil {
+		tt.Error("unexported key field: expected an error")
+	}
+}
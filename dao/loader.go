@@ -0,0 +1,176 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+)
+
+// Loader collects Load calls arriving within a window and reads them from
+// Firestore in a single batch. Documents, and the fact that one does not
+// exist, are cached for the life of the Loader, which is meant to be scoped to
+// a single request. Other errors are not, so that a later Load reads again.
+type Loader[Document any] struct {
+	ctx    context.Context
+	fetch  func(ctx context.Context, ids []string) map[string]loaded[Document]
+	window time.Duration
+	max    int
+
+	mu      sync.Mutex
+	pending *loaderBatch[Document]
+	loaded  map[string]*loaderBatch[Document]
+}
+
+type loaderBatch[Document any] struct {
+	ids     []string
+	done    chan struct{}
+	results map[string]loaded[Document]
+}
+
+type loaded[Document any] struct {
+	document Document
+	err      error
+}
+
+type loaderKey struct {
+	dao any
+}
+
+// NewLoader batches reads within window, and at most max IDs per batch. The
+// batches are read using ctx.
+func NewLoader[Document any](ctx context.Context, dao *DAO[Document], window time.Duration, max int) *Loader[Document] {
+
+	return &Loader[Document]{
+		ctx:    ctx,
+		fetch:  dao.readMany,
+		window: window,
+		max:    max,
+		loaded: map[string]*loaderBatch[Document]{},
+	}
+}
+
+// WithLoader returns a context carrying a new Loader for this DAO, used by
+// Load for the rest of the request.
+func (c *DAO[Document]) WithLoader(ctx context.Context, window time.Duration, max int) context.Context {
+
+	l := NewLoader(ctx, c, window, max)
+
+	return context.WithValue(ctx, loaderKey{dao: c}, l)
+}
+
+// Load reads through the Loader in ctx, or falls back to Read without one.
+func (c *DAO[Document]) Load(ctx context.Context, id string) (Document, error) {
+
+	l, found := ctx.Value(loaderKey{dao: c}).(*Loader[Document])
+	if !found {
+		return c.Read(ctx, id)
+	}
+
+	return l.Load(ctx, id)
+}
+
+func (l *Loader[Document]) Load(ctx context.Context, id string) (Document, error) {
+
+	l.mu.Lock()
+	b, found := l.loaded[id]
+	if !found {
+		b = l.enqueue(id)
+		l.loaded[id] = b
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		var empty Document
+		return empty, ctx.Err()
+	case <-b.done:
+	}
+
+	r := b.results[id]
+
+	return r.document, r.err
+}
+
+// enqueue must be called with l.mu held.
+func (l *Loader[Document]) enqueue(id string) *loaderBatch[Document] {
+
+	b := l.pending
+	if b == nil {
+		b = &loaderBatch[Document]{
+			done: make(chan struct{}),
+		}
+		l.pending = b
+		time.AfterFunc(l.window, func() {
+			l.mu.Lock()
+			due := l.pending == b
+			if due {
+				l.pending = nil
+			}
+			l.mu.Unlock()
+			if due {
+				l.load(b)
+			}
+		})
+	}
+
+	b.ids = append(b.ids, id)
+	if l.max > 0 && len(b.ids) >= l.max {
+		l.pending = nil
+		go l.load(b)
+	}
+
+	return b
+}
+
+func (l *Loader[Document]) load(b *loaderBatch[Document]) {
+
+	b.results = l.fetch(l.ctx, b.ids)
+
+	l.mu.Lock()
+	for _, id := range b.ids {
+		err := b.results[id].err
+		if err != nil && !errors.Is(err, e.ErrNotFound) && l.loaded[id] == b {
+			delete(l.loaded, id)
+		}
+	}
+	l.mu.Unlock()
+
+	close(b.done)
+}
+
+func (c *DAO[Document]) readMany(ctx context.Context, ids []string) map[string]loaded[Document] {
+
+	results := make(map[string]loaded[Document], len(ids))
+
+	collection := c.c.Collection(c.path)
+	refs := make([]*firestore.DocumentRef, len(ids))
+	for i, id := range ids {
+		refs[i] = collection.Doc(id)
+	}
+
+	snapshots, err := c.c.GetAll(ctx, refs)
+	if err != nil {
+		for _, id := range ids {
+			results[id] = loaded[Document]{err: err}
+		}
+		return results
+	}
+
+	for i, s := range snapshots {
+		id := ids[i]
+		if !s.Exists() {
+			cause := fmt.Sprintf("(ID: %s)", id)
+			results[id] = loaded[Document]{err: e.Wrap(cause, e.ErrNotFound)}
+			continue
+		}
+		d, err := c.fromSnapshot(s)
+		results[id] = loaded[Document]{document: d, err: err}
+	}
+
+	return results
+}
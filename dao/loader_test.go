@@ -0,0 +1,165 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+)
+
+// fakeFetch records the batches it is asked for, and reads IDs as documents,
+// failing the ones in errs.
+type fakeFetch struct {
+	mu      sync.Mutex
+	batches [][]string
+	errs    map[string]error
+}
+
+func (f *fakeFetch) fetch(ctx context.Context, ids []string) map[string]loaded[string] {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, append([]string(nil), ids...))
+
+	results := map[string]loaded[string]{}
+	for _, id := range ids {
+		if err, found := f.errs[id]; found {
+			results[id] = loaded[string]{err: err}
+			continue
+		}
+		results[id] = loaded[string]{document: id}
+	}
+	return results
+}
+
+func (f *fakeFetch) calls() [][]string {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.batches...)
+}
+
+func newTestLoader(f *fakeFetch, window time.Duration, max int) *Loader[string] {
+
+	return &Loader[string]{
+		ctx:    context.Background(),
+		fetch:  f.fetch,
+		window: window,
+		max:    max,
+		loaded: map[string]*loaderBatch[string]{},
+	}
+}
+
+// loadAll loads ids concurrently, returning the errors by ID.
+func loadAll(tt *testing.T, l *Loader[string], ids ...string) map[string]error {
+
+	var mu sync.Mutex
+	errs := map[string]error{}
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		id := id
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d, err := l.Load(context.Background(), id)
+			if err == nil && d != id {
+				tt.Errorf("got %q for %q", d, id)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			errs[id] = err
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
+
+func TestLoaderBatchesWindow(tt *testing.T) {
+
+	f := &fakeFetch{}
+	l := newTestLoader(f, 20*time.Millisecond, 0)
+
+	for id, err := range loadAll(tt, l, "a", "b", "c", "a") {
+		if err != nil {
+			tt.Errorf("%s: unexpected error: %v", id, err)
+		}
+	}
+
+	batches := f.calls()
+	if len(batches) != 1 {
+		tt.Fatalf("got batches %v, want one", batches)
+	}
+	sort.Strings(batches[0])
+	if len(batches[0]) != 3 || batches[0][0] != "a" || batches[0][2] != "c" {
+		tt.Errorf("got batch %v, want [a b c]", batches[0])
+	}
+}
+
+func TestLoaderFlushesAtMax(tt *testing.T) {
+
+	f := &fakeFetch{}
+	l := newTestLoader(f, time.Hour, 2)
+
+	done := make(chan map[string]error)
+	go func() {
+		done <- loadAll(tt, l, "a", "b")
+	}()
+
+	select {
+	case errs := <-done:
+		for id, err := range errs {
+			if err != nil {
+				tt.Errorf("%s: unexpected error: %v", id, err)
+			}
+		}
+	case <-time.After(time.Second):
+		tt.Fatal("a full batch was not read before its window")
+	}
+	if batches := f.calls(); len(batches) != 1 || len(batches[0]) != 2 {
+		tt.Errorf("got batches %v, want one of two IDs", batches)
+	}
+}
+
+func TestLoaderCachesNotFound(tt *testing.T) {
+
+	f := &fakeFetch{errs: map[string]error{"gone": e.Wrap("(ID: gone)", e.ErrNotFound)}}
+	l := newTestLoader(f, time.Millisecond, 0)
+
+	for i := 0; i < 2; i++ {
+		errs := loadAll(tt, l, "gone", "gone", "gone")
+		if len(errs) != 1 || !errors.Is(errs["gone"], e.ErrNotFound) {
+			tt.Errorf("got %v, want ErrNotFound", errs)
+		}
+	}
+	if batches := f.calls(); len(batches) != 1 {
+		tt.Errorf("got batches %v, want one", batches)
+	}
+}
+
+func TestLoaderRetriesFailures(tt *testing.T) {
+
+	unavailable := errors.New("unavailable")
+	f := &fakeFetch{errs: map[string]error{"a": unavailable}}
+	l := newTestLoader(f, time.Millisecond, 0)
+
+	errs := loadAll(tt, l, "a")
+	if !errors.Is(errs["a"], unavailable) {
+		tt.Errorf("got %v, want the fetch error", errs["a"])
+	}
+
+	f.mu.Lock()
+	f.errs = nil
+	f.mu.Unlock()
+	errs = loadAll(tt, l, "a")
+	if errs["a"] != nil {
+		tt.Errorf("unexpected error: %v", errs["a"])
+	}
+	if batches := f.calls(); len(batches) != 2 {
+		tt.Errorf("got batches %v, want two", batches)
+	}
+}
@@ -0,0 +1,334 @@
+package dao
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	t "github.com/pergamenum/go-consensus-standards/types"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// matches evaluates queries against document data the way Firestore would,
+// for use where a query cannot be sent to Firestore.
+func matches(id string, data map[string]any, queries []t.Query) (bool, error) {
+
+	for _, q := range queries {
+		ok, err := match(id, data, q)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func match(id string, data map[string]any, q t.Query) (bool, error) {
+
+	op := strings.ToUpper(q.Operator)
+
+	value, found := lookup(data, q.Key)
+	if q.Key == firestore.DocumentID {
+		value, found = id, true
+	}
+	if op == "MISSING" {
+		return !found, nil
+	}
+	if !found {
+		// Firestore never matches a missing field.
+		return false, nil
+	}
+
+	switch op {
+	case "EXISTS":
+		return true, nil
+	case "ISNULL":
+		return value == nil, nil
+	case "NOTNULL":
+		return value != nil, nil
+	case "ISNAN":
+		f, ok := value.(float64)
+		return ok && math.IsNaN(f), nil
+	case "EQ":
+		return equal(value, q.Value), nil
+	case "NE":
+		return value != nil && !equal(value, q.Value), nil
+	case "IN":
+		candidates := reflect.ValueOf(q.Value)
+		if candidates.Kind() != reflect.Slice {
+			cause := fmt.Sprintf("(operator IN needs a list, got %T)", q.Value)
+			return false, e.Wrap(cause, e.ErrBadRequest)
+		}
+		for i := 0; i < candidates.Len(); i++ {
+			if equal(value, candidates.Index(i).Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "PREFIX":
+		str, ok := value.(string)
+		prefix, _ := q.Value.(string)
+		return ok && strings.HasPrefix(str, prefix), nil
+	case "LT", "GT", "LE", "GE":
+		order, comparable := compare(value, q.Value)
+		if !comparable {
+			return false, nil
+		}
+		switch op {
+		case "LT":
+			return order < 0, nil
+		case "GT":
+			return order > 0, nil
+		case "LE":
+			return order <= 0, nil
+		default:
+			return order >= 0, nil
+		}
+	default:
+		cause := fmt.Sprintf("(unknown operator '%s')", q.Operator)
+		return false, e.Wrap(cause, e.ErrBadRequest)
+	}
+}
+
+// lookup resolves a dot-separated path in nested document data.
+func lookup(data map[string]any, path string) (any, bool) {
+
+	var value any = data
+	for _, segment := range strings.Split(path, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		value, ok = m[segment]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+func equal(a, b any) bool {
+
+	order, comparable := compare(a, b)
+	if comparable {
+		return order == 0
+	}
+
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// compare orders two values of the same Firestore type, reporting false when
+// they cannot be compared, like NaN with any number.
+func compare(a, b any) (int, bool) {
+
+	a, b = normalize(a), normalize(b)
+
+	switch av := a.(type) {
+	case int64, float64:
+		return compareNumbers(av, b)
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(av, bv), true
+	case bool:
+		bv, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case av == bv:
+			return 0, true
+		case !av:
+			return -1, true
+		default:
+			return 1, true
+		}
+	case time.Time:
+		bv, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case av.Before(bv):
+			return -1, true
+		case av.After(bv):
+			return 1, true
+		default:
+			return 0, true
+		}
+	case []byte:
+		bv, ok := b.([]byte)
+		if !ok {
+			return 0, false
+		}
+		return bytes.Compare(av, bv), true
+	default:
+		return 0, false
+	}
+}
+
+// compareNumbers orders two numbers exactly, including large integers that
+// float64 cannot represent.
+func compareNumbers(a, b any) (int, bool) {
+
+	switch av := a.(type) {
+	case int64:
+		switch bv := b.(type) {
+		case int64:
+			return compareInts(av, bv), true
+		case float64:
+			return compareIntFloat(av, bv)
+		}
+	case float64:
+		switch bv := b.(type) {
+		case int64:
+			order, ok := compareIntFloat(bv, av)
+			return -order, ok
+		case float64:
+			if math.IsNaN(av) || math.IsNaN(bv) {
+				return 0, false
+			}
+			switch {
+			case av < bv:
+				return -1, true
+			case av > bv:
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func compareInts(a, b int64) int {
+
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareIntFloat(i int64, f float64) (int, bool) {
+
+	switch {
+	case math.IsNaN(f):
+		return 0, false
+	case f >= math.MaxInt64:
+		// float64(math.MaxInt64) rounds up to 2^63, above every int64.
+		return -1, true
+	case f < math.MinInt64:
+		return 1, true
+	}
+
+	// Rounding is monotonic, so differing floats order the integers too.
+	switch fi := float64(i); {
+	case fi < f:
+		return -1, true
+	case fi > f:
+		return 1, true
+	}
+
+	// f is integral and within range here.
+	return compareInts(i, int64(f)), true
+}
+
+// isNaN reports whether value is a NaN number.
+func isNaN(value any) bool {
+
+	f, ok := normalize(value).(float64)
+	return ok && math.IsNaN(f)
+}
+
+// normalize brings Go values to the types returned by DocumentSnapshot.Data,
+// with integers as int64 and other numbers as float64.
+func normalize(value any) any {
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return float64(v.Uint())
+		}
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Pointer:
+		if ts, ok := value.(*time.Time); ok && ts != nil {
+			return *ts
+		}
+	}
+
+	return value
+}
+
+// rank orders values of different types the way Firestore does.
+func rank(value any) int {
+
+	switch normalize(value).(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int64, float64:
+		return 2
+	case time.Time:
+		return 3
+	case string:
+		return 4
+	case []byte:
+		return 5
+	case *firestore.DocumentRef:
+		return 6
+	case *latlng.LatLng:
+		return 7
+	case []any:
+		return 8
+	default:
+		return 9
+	}
+}
+
+// sortOrder orders any two values, falling back to Firestore's type order
+// for values that cannot be compared. NaN sorts before all other numbers.
+func sortOrder(a, b any) int {
+
+	order, comparable := compare(a, b)
+	if comparable {
+		return order
+	}
+	if rank(a) == rank(b) && (isNaN(a) || isNaN(b)) {
+		switch {
+		case isNaN(a) && isNaN(b):
+			return 0
+		case isNaN(a):
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	return rank(a) - rank(b)
+}
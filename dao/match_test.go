@@ -0,0 +1,147 @@
+package dao
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+func TestMatch(tt *testing.T) {
+
+	now := time.Now()
+	data := map[string]any{
+		"nan":    math.NaN(),
+		"big":    int64(1<<53 + 1),
+		"int":    int64(5),
+		"float":  5.0,
+		"str":    "jeff",
+		"null":   nil,
+		"ts":     now,
+		"nested": map[string]any{"n": int64(1)},
+	}
+
+	tests := []struct {
+		name string
+		q    t.Query
+		want bool
+	}{
+		{"NaN EQ", t.Query{Key: "nan", Operator: "EQ", Value: 5}, false},
+		{"NaN GE", t.Query{Key: "nan", Operator: "GE", Value: 5}, false},
+		{"NaN LT", t.Query{Key: "nan", Operator: "LT", Value: 5}, false},
+		{"NaN EQ NaN", t.Query{Key: "nan", Operator: "EQ", Value: math.NaN()}, false},
+		{"NaN ISNAN", t.Query{Key: "nan", Operator: "ISNAN"}, true},
+		{"int ISNAN", t.Query{Key: "int", Operator: "ISNAN"}, false},
+		{"big EQ neighbour", t.Query{Key: "big", Operator: "EQ", Value: int64(1 << 53)}, false},
+		{"big GT neighbour", t.Query{Key: "big", Operator: "GT", Value: int64(1 << 53)}, true},
+		{"big EQ itself", t.Query{Key: "big", Operator: "EQ", Value: 1<<53 + 1}, true},
+		{"int EQ float", t.Query{Key: "int", Operator: "EQ", Value: 5.0}, true},
+		{"float EQ int", t.Query{Key: "float", Operator: "EQ", Value: 5}, true},
+		{"int LT float", t.Query{Key: "int", Operator: "LT", Value: 5.5}, true},
+		{"int NE", t.Query{Key: "int", Operator: "NE", Value: 6}, true},
+		{"int IN", t.Query{Key: "int", Operator: "IN", Value: []int{1, 5}}, true},
+		{"int GT string", t.Query{Key: "int", Operator: "GT", Value: "a"}, false},
+		{"str PREFIX", t.Query{Key: "str", Operator: "PREFIX", Value: "je"}, true},
+		{"str LE", t.Query{Key: "str", Operator: "LE", Value: "jeff"}, true},
+		{"null ISNULL", t.Query{Key: "null", Operator: "ISNULL"}, true},
+		{"null NE", t.Query{Key: "null", Operator: "NE", Value: 1}, false},
+		{"ts GE", t.Query{Key: "ts", Operator: "GE", Value: now.Add(-time.Second)}, true},
+		{"nested EQ", t.Query{Key: "nested.n", Operator: "EQ", Value: 1}, true},
+		{"missing EQ", t.Query{Key: "missing", Operator: "EQ", Value: nil}, false},
+		{"missing MISSING", t.Query{Key: "missing", Operator: "MISSING"}, true},
+		{"document ID", t.Query{Key: "__name__", Operator: "EQ", Value: "id"}, true},
+	}
+
+	for _, test := range tests {
+		tt.Run(test.name, func(tt *testing.T) {
+			got, err := match("id", data, test.q)
+			if err != nil {
+				tt.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				tt.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	_, err := match("id", data, t.Query{Key: "int", Operator: "LIKE", Value: 1})
+	if err == nil {
+		tt.Error("unknown operator: expected an error")
+	}
+}
+
+func TestCompare(tt *testing.T) {
+
+	tests := []struct {
+		name       string
+		a, b       any
+		want       int
+		comparable bool
+	}{
+		{"ints", 1, 2, -1, true},
+		{"large ints", int64(1<<53 + 1), int64(1 << 53), 1, true},
+		{"int and float", int64(1<<53 + 1), float64(1 << 53), 1, true},
+		{"float and int", float64(1 << 53), int64(1<<53 + 1), -1, true},
+		{"int and fraction", 2, 1.5, 1, true},
+		{"max int and 2^63", int64(math.MaxInt64), float64(math.MaxInt64), -1, true},
+		{"min int", int64(math.MinInt64), float64(math.MinInt64), 0, true},
+		{"uint and int", uint8(3), int64(3), 0, true},
+		{"NaN and float", math.NaN(), 1.0, 0, false},
+		{"int and NaN", 1, math.NaN(), 0, false},
+		{"NaN and NaN", math.NaN(), math.NaN(), 0, false},
+		{"strings", "b", "a", 1, true},
+		{"bools", false, true, -1, true},
+		{"times", time.Unix(1, 0), time.Unix(1, 0), 0, true},
+		{"bytes", []byte("a"), []byte("b"), -1, true},
+		{"mixed types", 1, "1", 0, false},
+	}
+
+	for _, test := range tests {
+		tt.Run(test.name, func(tt *testing.T) {
+			got, comparable := compare(test.a, test.b)
+			if got != test.want || comparable != test.comparable {
+				tt.Errorf("got (%d, %v), want (%d, %v)", got, comparable, test.want, test.comparable)
+			}
+		})
+	}
+}
+
+func TestSortOrder(tt *testing.T) {
+
+	tests := []struct {
+		name string
+		a, b any
+		want int
+	}{
+		{"NaN before numbers", math.NaN(), math.Inf(-1), -1},
+		{"numbers after NaN", int64(math.MinInt64), math.NaN(), 1},
+		{"NaN and NaN", math.NaN(), math.NaN(), 0},
+		{"NaN after bools", math.NaN(), true, 1},
+		{"null first", nil, false, -1},
+		{"numbers before strings", 10, "1", -1},
+		{"strings before bytes", "b", []byte("a"), -1},
+		{"numbers", 2.5, 2, 1},
+	}
+
+	for _, test := range tests {
+		tt.Run(test.name, func(tt *testing.T) {
+			got := sortOrder(test.a, test.b)
+			if sign(got) != test.want {
+				tt.Errorf("got %d, want sign %d", got, test.want)
+			}
+		})
+	}
+}
+
+func sign(n int) int {
+
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+// Model is meant to be embedded in Document structs. The DAO fills in ID,
+// Created and Updated on reads, stamps Created, Updated and Version on writes,
+// and uses Version for optimistic concurrency in UpdateVersion.
+type Model struct {
+	ID      string    `firestore:"-" json:"id" dao:"id"`
+	Created time.Time `firestore:"created" json:"created" dao:"created"`
+	Updated time.Time `firestore:"updated" json:"updated" dao:"updated"`
+	Version int64     `firestore:"version" json:"version" dao:"version"`
+}
+
+// UpdateVersion applies update only if the document is still at version,
+// returning ErrConflict if someone else updated it first.
+func (c *DAO[Document]) UpdateVersion(ctx context.Context, id string, version int64, update t.Update) error {
+
+	if c.managed.versionName == "" {
+		cause := fmt.Sprintf("(documents of '%s' have no version field)", c.path)
+		return e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	queries := []t.Query{
+		{
+			Key:      c.managed.versionName,
+			Operator: "EQ",
+			Value:    version,
+		},
+	}
+
+	return c.UpdateIf(ctx, id, queries, update)
+}
@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"time"
+)
+
+type config struct {
+	slow           map[Operation]time.Duration
+	slowFirst      int
+	slowThereafter int
+
+	coalesce        bool
+	coalesceTimeout time.Duration
+
+	clientFilters bool
+	planner       bool
+	maxScanned    int
+
+	key *Key
+}
+
+type Option func(*config)
+
+func newConfig(opts []Option) config {
+
+	cfg := config{
+		slow:           map[Operation]time.Duration{},
+		slowFirst:      10,
+		slowThereafter: 100,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// WithSlowThreshold logs a warning when op takes longer than threshold. An
+// empty op sets the threshold for every operation without its own.
+func WithSlowThreshold(op Operation, threshold time.Duration) Option {
+	return func(cfg *config) {
+		cfg.slow[op] = threshold
+	}
+}
+
+// WithSlowSampling logs the first slow warnings per second, and thereafter
+// only every thereafter:th one.
+func WithSlowSampling(first, thereafter int) Option {
+	return func(cfg *config) {
+		cfg.slowFirst = first
+		cfg.slowThereafter = thereafter
+	}
+}
+
+// defaultCoalesceTimeout bounds shared calls when WithCoalescing is given no
+// timeout, so that a hung call cannot block its key forever.
+const defaultCoalesceTimeout = 30 * time.Second
+
+// WithCoalescing lets concurrent Read calls for the same ID, and Search calls
+// with identical queries, share a single Firestore call. The shared call is
+// bounded by timeout instead of the callers' contexts, zero or less means 30
+// seconds. Callers sharing a call get the same Document, so
+// maps, slices and pointers in it are shared too, and must not be modified.
+func WithCoalescing(timeout time.Duration) Option {
+	return func(cfg *config) {
+		if timeout <= 0 {
+			timeout = defaultCoalesceTimeout
+		}
+		cfg.coalesce = true
+		cfg.coalesceTimeout = timeout
+	}
+}
+
+// WithClientFilters lets Search accept operators evaluated client-side, at
+// the cost of reading every document matching the other queries.
+func WithClientFilters() Option {
+	return func(cfg *config) {
+		cfg.clientFilters = true
+	}
+}
+
+// WithPlanner lets Search accept any combination of queries, by sending
+// Firestore the ones it supports and evaluating the rest client-side. This
+// implies WithClientFilters. Queries scanning more than maxScanned documents
+// fail with ErrBadRequest, zero means no limit.
+func WithPlanner(maxScanned int) Option {
+	return func(cfg *config) {
+		cfg.clientFilters = true
+		cfg.planner = true
+		cfg.maxScanned = maxScanned
+	}
+}
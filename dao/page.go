@@ -0,0 +1,83 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+// maxOffset bounds how many documents a page may skip, since Firestore bills
+// skipped documents as reads.
+const maxOffset = 10000
+
+type Page[Document any] struct {
+	Items []Document `json:"items"`
+	Total int64      `json:"total"`
+	// Page is 1-based.
+	Page  int `json:"page"`
+	Size  int `json:"size"`
+	Pages int `json:"pages"`
+}
+
+// Paginate returns page number page, 1-based, of size documents matching
+// queries, along with the total number of matching documents.
+func (c *DAO[Document]) Paginate(ctx context.Context, queries []t.Query, page, size int) (Page[Document], error) {
+
+	log := c.log.Named("Paginate")
+
+	if page < 1 || size < 1 {
+		cause := fmt.Sprintf("(invalid page %d of size %d)", page, size)
+		return Page[Document]{}, e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	offset := (page - 1) * size
+	if offset > maxOffset {
+		cause := fmt.Sprintf("(offset %d exceeds the maximum of %d)", offset, maxOffset)
+		return Page[Document]{}, e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	fsq, err := c.query(queries)
+	if err != nil {
+		return Page[Document]{}, err
+	}
+
+	total, err := count(ctx, fsq)
+	if err != nil {
+		return Page[Document]{}, err
+	}
+
+	snapshots, err := fsq.Offset(offset).Limit(size).Documents(ctx).GetAll()
+	if err != nil {
+		return Page[Document]{}, queryError(err)
+	}
+
+	return Page[Document]{
+		Items: c.decode(log, snapshots),
+		Total: total,
+		Page:  page,
+		Size:  size,
+		Pages: int((total + int64(size) - 1) / int64(size)),
+	}, nil
+}
+
+// count runs a count aggregation, which is billed per batch of index entries
+// rather than per document.
+func count(ctx context.Context, fsq firestore.Query) (int64, error) {
+
+	result, err := fsq.NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		return 0, queryError(err)
+	}
+
+	count, ok := result["count"].(*pb.Value)
+	if !ok {
+		cause := fmt.Sprintf("(unexpected count aggregation result: %v)", result)
+		return 0, e.Wrap(cause, e.ErrCorrupt)
+	}
+
+	return count.GetIntegerValue(), nil
+}
@@ -0,0 +1,128 @@
+package dao
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	"go.uber.org/zap"
+)
+
+// Registry constructs the DAOs of a service, sharing a client, logger and
+// options, and validating collection paths up front.
+type Registry struct {
+	c    *firestore.Client
+	log  *zap.SugaredLogger
+	opts []Option
+
+	mu    sync.Mutex
+	paths map[string]bool
+}
+
+func NewRegistry(fc *firestore.Client, log *zap.SugaredLogger, opts ...Option) *Registry {
+
+	return &Registry{
+		c:     fc,
+		log:   log,
+		opts:  opts,
+		paths: map[string]bool{},
+	}
+}
+
+// Register validates path and returns a DAO for it, configured with the
+// registry's options followed by opts. Each path can only be registered once.
+func Register[Document any](r *Registry, path string, opts ...Option) (*DAO[Document], error) {
+
+	err := r.add(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDAO[Document](r.c, path, r.log, r.options(opts)...), nil
+}
+
+// MustRegister is like Register but panics on error, for use during startup.
+func MustRegister[Document any](r *Registry, path string, opts ...Option) *DAO[Document] {
+
+	dao, err := Register[Document](r, path, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return dao
+}
+
+// RegisterTyped is like Register for a TypedDAO.
+func RegisterTyped[ID Identifier, Document any](r *Registry, path string, parse func(string) (ID, error), opts ...Option) (*TypedDAO[ID, Document], error) {
+
+	err := r.add(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTypedDAO[ID, Document](r.c, path, r.log, parse, r.options(opts)...), nil
+}
+
+// Collections lists the registered collection paths, sorted.
+func (r *Registry) Collections() []string {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths := make([]string, 0, len(r.paths))
+	for path := range r.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+func (r *Registry) add(path string) error {
+
+	err := ValidatePath(path)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.paths[path] {
+		cause := fmt.Sprintf("(collection '%s' is already registered)", path)
+		return e.Wrap(cause, e.ErrConflict)
+	}
+	r.paths[path] = true
+
+	return nil
+}
+
+func (r *Registry) options(opts []Option) []Option {
+
+	return append(r.opts[:len(r.opts):len(r.opts)], opts...)
+}
+
+// ValidatePath checks that path names a collection, like "users" or
+// "users/jeff/orders", using only IDs Firestore accepts.
+func ValidatePath(path string) error {
+
+	segments := strings.Split(path, "/")
+	if len(segments)%2 == 0 {
+		cause := fmt.Sprintf("(path '%s' has %d segments, a collection needs an odd number)", path, len(segments))
+		return e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	for _, segment := range segments {
+		problem := idProblem(segment)
+		if problem == "" {
+			continue
+		}
+		cause := fmt.Sprintf("(segment '%s' of path '%s' %s)", segment, path, problem)
+		return e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	return nil
+}
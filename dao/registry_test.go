@@ -0,0 +1,67 @@
+package dao
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestValidatePath(tt *testing.T) {
+
+	valid := []string{
+		"users",
+		"users/jeff/orders",
+		"a/b/c/d/e",
+		"_users_",
+		"__users",
+		strings.Repeat("x", maxSegmentBytes),
+	}
+	for _, path := range valid {
+		if err := ValidatePath(path); err != nil {
+			tt.Errorf("'%s': unexpected error: %v", path, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"users/jeff",
+		"/users",
+		"users/",
+		"users//orders",
+		"users/./orders",
+		"..",
+		"__users__",
+		"users/__jeff__/orders",
+		strings.Repeat("x", maxSegmentBytes+1),
+	}
+	for _, path := range invalid {
+		if err := ValidatePath(path); err == nil {
+			tt.Errorf("'%s': expected an error", path)
+		}
+	}
+}
+
+func TestRegistry(tt *testing.T) {
+
+	r := NewRegistry(nil, zap.NewNop().Sugar())
+
+	_, err := Register[string](r, "users")
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+	_, err = Register[string](r, "users")
+	if err == nil {
+		tt.Error("registering a path twice: expected an error")
+	}
+	_, err = Register[string](r, "users/jeff")
+	if err == nil {
+		tt.Error("registering a document path: expected an error")
+	}
+	MustRegister[string](r, "orders")
+
+	got := strings.Join(r.Collections(), ",")
+	if got != "orders,users" {
+		tt.Errorf("got collections '%s', want 'orders,users'", got)
+	}
+}
@@ -0,0 +1,29 @@
+package dao
+
+import (
+	"context"
+
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+// Repository is the set of operations offered by DAO, so that wrappers can be
+// stacked around it.
+type Repository[Document any] interface {
+	Create(ctx context.Context, id string, document Document) error
+	Read(ctx context.Context, id string) (Document, error)
+	Update(ctx context.Context, id string, update t.Update) error
+	Delete(ctx context.Context, id string) error
+	Search(ctx context.Context, queries []t.Query) ([]Document, error)
+}
+
+type Operation string
+
+const (
+	OpCreate Operation = "Create"
+	OpRead   Operation = "Read"
+	OpUpdate Operation = "Update"
+	OpDelete Operation = "Delete"
+	OpSearch Operation = "Search"
+)
+
+var _ Repository[any] = (*DAO[any])(nil)
@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"context"
+	"sync"
+
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+// fakeRepository records the calls made to it, and holds updates on gate, if
+// set, until it receives.
+type fakeRepository struct {
+	mu       sync.Mutex
+	calls    []Operation
+	updates  []t.Update
+	inFlight int
+	overlaps int
+	gate     chan struct{}
+}
+
+var _ Repository[string] = (*fakeRepository)(nil)
+
+func (r *fakeRepository) record(op Operation) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, op)
+}
+
+func (r *fakeRepository) Create(ctx context.Context, id string, document string) error {
+
+	r.record(OpCreate)
+	return nil
+}
+
+func (r *fakeRepository) Read(ctx context.Context, id string) (string, error) {
+
+	r.record(OpRead)
+	return id, nil
+}
+
+func (r *fakeRepository) Update(ctx context.Context, id string, update t.Update) error {
+
+	r.mu.Lock()
+	r.calls = append(r.calls, OpUpdate)
+	r.inFlight++
+	if r.inFlight > 1 {
+		r.overlaps++
+	}
+	gate := r.gate
+	r.mu.Unlock()
+
+	if gate != nil {
+		<-gate
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight--
+	r.updates = append(r.updates, update)
+	return nil
+}
+
+func (r *fakeRepository) Delete(ctx context.Context, id string) error {
+
+	r.record(OpDelete)
+	return nil
+}
+
+func (r *fakeRepository) Search(ctx context.Context, queries []t.Query) ([]string, error) {
+
+	r.record(OpSearch)
+	return nil, nil
+}
@@ -0,0 +1,93 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	t "github.com/pergamenum/go-consensus-standards/types"
+)
+
+const autoIDAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// sampleAttempts bounds the number of queries per requested document, since
+// random keys may land on documents already sampled.
+const sampleAttempts = 3
+
+// Sample returns up to n documents matching queries, picked by seeking to
+// random document IDs. The sample is approximately uniform as long as the
+// collection uses random IDs, like Firestore's auto-generated ones. Each
+// sampled document costs one query. Since seeking orders by the document ID,
+// inequality filters are only supported on the document ID.
+func (c *DAO[Document]) Sample(ctx context.Context, n int, queries []t.Query) ([]Document, error) {
+
+	log := c.log.Named("Sample")
+
+	for _, q := range queries {
+		if inequality(q.Operator) && q.Key != firestore.DocumentID {
+			cause := fmt.Sprintf("(sampling orders by document ID, so it cannot filter '%s' with '%s')", q.Key, q.Operator)
+			return nil, e.Wrap(cause, e.ErrBadRequest)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	fsq, err := c.query(queries)
+	if err != nil {
+		return nil, err
+	}
+	fsq = fsq.OrderBy(firestore.DocumentID, firestore.Asc)
+
+	seen := map[string]bool{}
+	var snapshots []*firestore.DocumentSnapshot
+	for attempt := 0; attempt < n*sampleAttempts && len(snapshots) < n; attempt++ {
+		s, err := c.seek(ctx, fsq, randomID(rng))
+		if err != nil {
+			return nil, err
+		}
+		if s == nil {
+			// Nothing matches the queries.
+			break
+		}
+		if seen[s.Ref.ID] {
+			continue
+		}
+		seen[s.Ref.ID] = true
+		snapshots = append(snapshots, s)
+	}
+
+	return c.decode(log, snapshots), nil
+}
+
+// seek returns the first document at or after key, wrapping around to the
+// start of the collection.
+func (c *DAO[Document]) seek(ctx context.Context, fsq firestore.Query, key string) (*firestore.DocumentSnapshot, error) {
+
+	snapshots, err := fsq.StartAt(key).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, queryError(err)
+	}
+	if len(snapshots) == 0 {
+		snapshots, err = fsq.Limit(1).Documents(ctx).GetAll()
+		if err != nil {
+			return nil, queryError(err)
+		}
+	}
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+
+	return snapshots[0], nil
+}
+
+func randomID(rng *rand.Rand) string {
+
+	id := make([]byte, 20)
+	for i := range id {
+		id[i] = autoIDAlphabet[rng.Intn(len(autoIDAlphabet))]
+	}
+
+	return string(id)
+}
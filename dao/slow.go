@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"strings"
+	"time"
+
+	t "github.com/pergamenum/go-consensus-standards/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newSlowLogger(log *zap.SugaredLogger, cfg config) *zap.SugaredLogger {
+
+	sampled := log.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, time.Second, cfg.slowFirst, cfg.slowThereafter)
+	}))
+
+	return sampled.Sugar().Named("slow")
+}
+
+func (c *DAO[Document]) warnSlow(op Operation, start time.Time, keysAndValues ...any) {
+
+	threshold, found := c.cfg.slow[op]
+	if !found {
+		threshold, found = c.cfg.slow[""]
+	}
+	if !found {
+		return
+	}
+
+	duration := time.Since(start)
+	if duration < threshold {
+		return
+	}
+
+	// The sampler counts entries per message, so each operation needs its own
+	// for a burst of one not to hide the others.
+	c.slowLog.With(
+		"operation", op,
+		"collection", c.path,
+		"duration", duration,
+		"threshold", threshold,
+	).With(keysAndValues...).
+		Warn("slow firestore " + op)
+}
+
+// queryShape describes queries without their values, which may be sensitive.
+func queryShape(queries []t.Query) string {
+
+	var shape []string
+	for _, q := range queries {
+		shape = append(shape, q.Key+" "+strings.ToUpper(q.Operator))
+	}
+
+	return strings.Join(shape, ", ")
+}
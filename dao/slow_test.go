@@ -0,0 +1,33 @@
+package dao
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSlowSamplingPerOperation(tt *testing.T) {
+
+	core, logs := observer.New(zap.WarnLevel)
+	c := NewDAO[string](nil, "users", zap.New(core).Sugar(),
+		WithSlowThreshold("", 0),
+		WithSlowSampling(1, 1000),
+	)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		c.warnSlow(OpRead, start)
+	}
+	c.warnSlow(OpDelete, start)
+
+	counts := map[string]int{}
+	for _, entry := range logs.All() {
+		counts[fmt.Sprint(entry.ContextMap()["operation"])]++
+	}
+	if counts[string(OpRead)] != 1 || counts[string(OpDelete)] != 1 {
+		tt.Errorf("got warnings %v, want one per operation", counts)
+	}
+}
@@ -0,0 +1,123 @@
+package dao
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+type Stats struct {
+	Collection string `json:"collection"`
+	Documents  int    `json:"documents"`
+	// Bytes is the approximate storage size, according to Firestore's
+	// storage size calculation rules. Indexes are not included.
+	Bytes int64 `json:"bytes"`
+	// Fields counts the documents each top-level field is present in.
+	Fields map[string]int `json:"fields"`
+	// CreatedByDay and UpdatedByDay count documents by UTC day, formatted as
+	// 2006-01-02, of their managed timestamps.
+	CreatedByDay map[string]int `json:"createdByDay"`
+	UpdatedByDay map[string]int `json:"updatedByDay"`
+}
+
+// Stats streams the whole collection, which costs one read per document.
+func (c *DAO[Document]) Stats(ctx context.Context) (Stats, error) {
+
+	stats := Stats{
+		Collection:   c.path,
+		Fields:       map[string]int{},
+		CreatedByDay: map[string]int{},
+		UpdatedByDay: map[string]int{},
+	}
+
+	it := c.c.Collection(c.path).Documents(ctx)
+	defer it.Stop()
+
+	for {
+		s, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return Stats{}, err
+		}
+
+		data := s.Data()
+		stats.Documents++
+		stats.Bytes += documentSize(s.Ref, data)
+		for field := range data {
+			stats.Fields[field]++
+		}
+		if created, ok := data["created"].(time.Time); ok {
+			stats.CreatedByDay[created.UTC().Format("2006-01-02")]++
+		}
+		if updated, ok := data["updated"].(time.Time); ok {
+			stats.UpdatedByDay[updated.UTC().Format("2006-01-02")]++
+		}
+	}
+
+	return stats, nil
+}
+
+// documentSize follows https://firebase.google.com/docs/firestore/storage-size
+func documentSize(ref *firestore.DocumentRef, data map[string]any) int64 {
+
+	return documentNameSize(ref) + mapSize(data) + 32
+}
+
+func documentNameSize(ref *firestore.DocumentRef) int64 {
+
+	name := ref.Path
+	if i := strings.Index(name, "/documents/"); i >= 0 {
+		name = name[i+len("/documents/"):]
+	}
+
+	size := int64(16)
+	for _, segment := range strings.Split(name, "/") {
+		size += int64(len(segment)) + 1
+	}
+
+	return size
+}
+
+func mapSize(data map[string]any) int64 {
+
+	var size int64
+	for field, value := range data {
+		size += int64(len(field)) + 1 + valueSize(value)
+	}
+
+	return size
+}
+
+func valueSize(value any) int64 {
+
+	switch v := value.(type) {
+	case nil, bool:
+		return 1
+	case int64, float64, time.Time:
+		return 8
+	case string:
+		return int64(len(v)) + 1
+	case []byte:
+		return int64(len(v))
+	case *latlng.LatLng:
+		return 16
+	case *firestore.DocumentRef:
+		return documentNameSize(v)
+	case []any:
+		var size int64
+		for _, element := range v {
+			size += valueSize(element)
+		}
+		return size
+	case map[string]any:
+		return mapSize(v)
+	default:
+		return 0
+	}
+}
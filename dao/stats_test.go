@@ -0,0 +1,67 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestDocumentSize(tt *testing.T) {
+
+	// The examples of https://firebase.google.com/docs/firestore/storage-size
+	ref := &firestore.DocumentRef{
+		Path: "projects/p/databases/(default)/documents/users/jeff/tasks/my_task_id",
+		ID:   "my_task_id",
+	}
+	task := map[string]any{
+		"type":        "Personal",
+		"done":        false,
+		"priority":    int64(1),
+		"description": "Learn Cloud Firestore",
+	}
+
+	if got := documentNameSize(ref); got != 44 {
+		tt.Errorf("document name: got %d, want 44", got)
+	}
+	if got := documentSize(ref, task); got != 147 {
+		tt.Errorf("document: got %d, want 147", got)
+	}
+}
+
+func TestValueSize(tt *testing.T) {
+
+	ref := &firestore.DocumentRef{
+		Path: "projects/p/databases/(default)/documents/users/jeff/tasks/my_task_id",
+		ID:   "my_task_id",
+	}
+
+	tests := []struct {
+		name  string
+		value any
+		want  int64
+	}{
+		{"null", nil, 1},
+		{"boolean", true, 1},
+		{"integer", int64(1), 8},
+		{"float", 1.5, 8},
+		{"timestamp", time.Now(), 8},
+		{"string", "my_string", 10},
+		{"bytes", []byte("abc"), 3},
+		{"geo point", &latlng.LatLng{}, 16},
+		{"reference", ref, 44},
+		{"array", []any{"my_string", int64(1), nil}, 10 + 8 + 1},
+		{"map", map[string]any{"type": "Personal", "done": false}, 5 + 9 + 5 + 1},
+		{"nested map", map[string]any{"a": map[string]any{"b": int64(1)}}, 2 + 2 + 8},
+		{"array of maps", []any{map[string]any{"b": true}}, 2 + 1},
+	}
+
+	for _, test := range tests {
+		tt.Run(test.name, func(tt *testing.T) {
+			if got := valueSize(test.value); got != test.want {
+				tt.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
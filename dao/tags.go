@@ -0,0 +1,261 @@
+package dao
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+)
+
+// managed locates the Document struct fields tagged for the DAO to manage:
+//
+//	dao:"id"       filled with the document ID on reads
+//	dao:"created"  filled with the managed creation timestamp on reads
+//	dao:"updated"  filled with the managed update timestamp on reads
+//	dao:"version"  set to 1 on Create and incremented by every update
+//
+// Tagged fields are left out of the data written on Create.
+type managed struct {
+	id      []int
+	created []int
+	updated []int
+	version []int
+	// versionName is the Firestore name of the version field, if any.
+	versionName string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func managedFields(document any) managed {
+
+	var m managed
+
+	typ := reflect.TypeOf(document)
+	if typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return m
+	}
+
+	for _, f := range reflect.VisibleFields(typ) {
+		switch f.Tag.Get("dao") {
+		case "id":
+			m.id = f.Index
+		case "created":
+			m.created = f.Index
+		case "updated":
+			m.updated = f.Index
+		case "version":
+			m.version = f.Index
+			m.versionName = storedName(f)
+		}
+	}
+
+	return m
+}
+
+func isManaged(f reflect.StructField) bool {
+
+	switch f.Tag.Get("dao") {
+	case "id", "created", "updated", "version":
+		return true
+	default:
+		return false
+	}
+}
+
+// storedName is the name Firestore stores f under, or empty if it does not.
+func storedName(f reflect.StructField) string {
+
+	name, _, _ := strings.Cut(f.Tag.Get("firestore"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	default:
+		return name
+	}
+}
+
+// fromSnapshot decodes s, filling in the managed fields.
+func (c *DAO[Document]) fromSnapshot(s *firestore.DocumentSnapshot) (Document, error) {
+
+	var d Document
+	err := s.DataTo(&d)
+	if err != nil {
+		cause := fmt.Sprintf("(firestore serialization failed: %s)", err.Error())
+		return c.empty, e.Wrap(cause, e.ErrCorrupt)
+	}
+
+	v, ok := structValue(&d)
+	if !ok {
+		return d, nil
+	}
+
+	err = c.setID(v, s.Ref.ID)
+	if err != nil {
+		cause := fmt.Sprintf("(decoding ID '%s' failed: %s)", s.Ref.ID, err.Error())
+		return c.empty, e.Wrap(cause, e.ErrCorrupt)
+	}
+	setTime(v, c.managed.created, s, "created")
+	setTime(v, c.managed.updated, s, "updated")
+
+	return d, nil
+}
+
+// created fills in the managed fields of d the way a read right after Create
+// would.
+func (c *DAO[Document]) created(d Document, id string, now time.Time) (Document, error) {
+
+	v, ok := structValue(&d)
+	if !ok {
+		return d, nil
+	}
+
+	err := c.setID(v, id)
+	if err != nil {
+		cause := fmt.Sprintf("(decoding ID '%s' failed: %s)", id, err.Error())
+		return c.empty, e.Wrap(cause, e.ErrBadRequest)
+	}
+	for _, index := range [][]int{c.managed.created, c.managed.updated} {
+		field, err := fieldByIndex(v, index)
+		if err == nil && field.Type() == timeType {
+			field.Set(reflect.ValueOf(now))
+		}
+	}
+	field, err := fieldByIndex(v, c.managed.version)
+	if err == nil && field.CanInt() {
+		field.SetInt(1)
+	}
+
+	return d, nil
+}
+
+// structValue is the settable struct d holds, directly or through a pointer.
+func structValue[Document any](d *Document) (reflect.Value, bool) {
+
+	v := reflect.ValueOf(d).Elem()
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+
+	return v, v.Kind() == reflect.Struct
+}
+
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+
+	if index == nil {
+		return reflect.Value{}, fmt.Errorf("no such field")
+	}
+
+	return v.FieldByIndexErr(index)
+}
+
+// setID sets the ID field of v to id, decoded by the ID parser if the field
+// has its type, as a string, or as text.
+func (c *DAO[Document]) setID(v reflect.Value, id string) error {
+
+	field, err := fieldByIndex(v, c.managed.id)
+	if err != nil {
+		return nil
+	}
+	if c.parseID != nil && field.Type() == c.parseID.typ {
+		parsed, err := c.parseID.parse(id)
+		if err != nil {
+			return err
+		}
+		field.Set(parsed)
+		return nil
+	}
+	if field.Kind() == reflect.String {
+		field.SetString(id)
+		return nil
+	}
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(id))
+	}
+
+	return fmt.Errorf("unsupported ID field type %s", field.Type())
+}
+
+func setTime(v reflect.Value, index []int, s *firestore.DocumentSnapshot, path string) {
+
+	if index == nil {
+		return
+	}
+	field, err := v.FieldByIndexErr(index)
+	if err != nil || field.Type() != timeType {
+		return
+	}
+	value, err := s.DataAt(path)
+	if err != nil {
+		return
+	}
+	if ts, ok := value.(time.Time); ok {
+		field.Set(reflect.ValueOf(ts))
+	}
+}
+
+// tagged reports whether Document has managed fields, in which case Create
+// writes createData instead of the document itself.
+func (m managed) tagged() bool {
+	return m.id != nil || m.created != nil || m.updated != nil || m.version != nil
+}
+
+// createData is what Create writes for a document with managed fields: its
+// fields as Firestore stores them, except the managed ones, plus the managed
+// timestamps and version.
+func (c *DAO[Document]) createData(document Document, now time.Time) (map[string]any, error) {
+
+	v := reflect.ValueOf(document)
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		cause := fmt.Sprintf("(documents with dao tags must be structs, got %T)", document)
+		return nil, e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	data, err := storedFields(v)
+	if err != nil {
+		cause := fmt.Sprintf("(encoding %T failed: %s)", document, err.Error())
+		return nil, e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	data["created"] = now
+	data["updated"] = now
+	if c.managed.versionName != "" {
+		data[c.managed.versionName] = int64(1)
+	}
+
+	return data, nil
+}
+
+// touched stamps the update timestamp, and increments the version, of an
+// updated document.
+func (c *DAO[Document]) touched(now time.Time) []firestore.Update {
+
+	fus := []firestore.Update{
+		{
+			Path:  "updated",
+			Value: now,
+		},
+	}
+	if c.managed.versionName != "" {
+		fus = append(fus, firestore.Update{
+			FieldPath: firestore.FieldPath{c.managed.versionName},
+			Value:     firestore.Increment(1),
+		})
+	}
+
+	return fus
+}
@@ -0,0 +1,131 @@
+package dao
+
+import (
+	"testing"
+	"time"
+)
+
+type tagged struct {
+	Model
+	Key     string    `firestore:"id" dao:"id"`
+	Name    string    `firestore:"name"`
+	Note    string    `firestore:"note,omitempty"`
+	Skipped string    `firestore:"-"`
+	Stamp   time.Time `firestore:"stamp,serverTimestamp"`
+	hidden  string
+}
+
+type point struct {
+	X, Y int
+}
+
+type left struct {
+	Shared string
+	Left   string
+}
+
+type right struct {
+	Shared string
+}
+
+type encoded struct {
+	Model
+	left
+	right
+	Stamp    time.Time  `firestore:"stamp,serverTimestamp"`
+	StampPtr *time.Time `firestore:"stampPtr,serverTimestamp"`
+	Origin   point      `firestore:"origin,omitempty"`
+	Pair     [2]int     `firestore:"pair,omitempty"`
+	None     [0]int     `firestore:"none,omitempty"`
+	When     time.Time  `firestore:"when,omitempty"`
+	Tags     []string   `firestore:"tags,omitempty"`
+}
+
+type badOption struct {
+	ID   string `firestore:"-" dao:"id"`
+	Name string `firestore:"name,omitEmpty"`
+}
+
+func TestCreateData(tt *testing.T) {
+
+	c := &DAO[tagged]{managed: managedFields(tagged{})}
+	now := time.Now()
+
+	data, err := c.createData(tagged{Key: "k", Name: "jeff", Skipped: "s", hidden: "h"}, now)
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"id", "ID", "Key", "note", "Skipped", "hidden", "Model", "Created", "Updated"} {
+		if _, found := data[name]; found {
+			tt.Errorf("field '%s' should not be written", name)
+		}
+	}
+	if data["name"] != "jeff" {
+		tt.Errorf("name: got %v", data["name"])
+	}
+	if data["created"] != now || data["updated"] != now {
+		tt.Errorf("timestamps: got %v and %v", data["created"], data["updated"])
+	}
+	if data["version"] != int64(1) {
+		tt.Errorf("version: got %v", data["version"])
+	}
+	if _, found := data["stamp"]; !found {
+		tt.Error("stamp should be set by the server")
+	}
+
+	d, err := c.created(tagged{Name: "jeff"}, "k", now)
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+	if d.Key != "k" || !d.Created.Equal(now) || !d.Updated.Equal(now) || d.Version != 1 {
+		tt.Errorf("created: got %+v", d)
+	}
+}
+
+func TestCreateDataEncoding(tt *testing.T) {
+
+	c := &DAO[encoded]{managed: managedFields(encoded{})}
+	stamp := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	data, err := c.createData(encoded{Stamp: stamp, StampPtr: &stamp, Tags: []string{}}, time.Now())
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+
+	// Like Firestore, non-zero serverTimestamp values are left out, and
+	// omitempty keeps zero structs and non-empty arrays.
+	for _, name := range []string{"stamp", "stampPtr", "none", "when", "tags", "Shared", "ID"} {
+		if value, found := data[name]; found {
+			tt.Errorf("'%s' should be left out, got %v", name, value)
+		}
+	}
+	if data["origin"] != (point{}) {
+		tt.Errorf("origin: got %v, want the zero point", data["origin"])
+	}
+	if data["pair"] != [2]int{} {
+		tt.Errorf("pair: got %v, want the zero array", data["pair"])
+	}
+	if data["Left"] != "" {
+		tt.Errorf("Left: got %v, want it promoted", data["Left"])
+	}
+
+	data, err = c.createData(encoded{}, time.Now())
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"stamp", "stampPtr"} {
+		if _, found := data[name]; !found {
+			tt.Errorf("'%s' should be set by the server", name)
+		}
+	}
+
+	_, err = (&DAO[badOption]{managed: managedFields(badOption{})}).createData(badOption{}, time.Now())
+	if err == nil {
+		tt.Error("unknown tag option: expected an error")
+	}
+
+	if (&DAO[point]{managed: managedFields(point{})}).managed.tagged() {
+		tt.Error("a document without dao tags should not take the createData path")
+	}
+}
@@ -0,0 +1,117 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"cloud.google.com/go/firestore"
+	e "github.com/pergamenum/go-consensus-standards/ehandler"
+	t "github.com/pergamenum/go-consensus-standards/types"
+	"go.uber.org/zap"
+)
+
+// Identifier is a document ID type of its own, so that IDs of different
+// collections cannot be mixed up. It is stored as the string it encodes to.
+type Identifier interface {
+	comparable
+	String() string
+}
+
+// idParser decodes document IDs into values of typ, for a TypedDAO.
+type idParser struct {
+	typ   reflect.Type
+	parse func(string) (reflect.Value, error)
+}
+
+// TypedDAO is a DAO whose methods take document IDs of type ID instead of
+// strings.
+type TypedDAO[ID Identifier, Document any] struct {
+	dao   *DAO[Document]
+	parse func(string) (ID, error)
+}
+
+// NewTypedDAO uses parse to decode document IDs read from Firestore into the
+// dao:"id" field of documents, when that field is of type ID.
+func NewTypedDAO[ID Identifier, Document any](fc *firestore.Client, path string, log *zap.SugaredLogger, parse func(string) (ID, error), opts ...Option) *TypedDAO[ID, Document] {
+
+	dao := NewDAO[Document](fc, path, log, opts...)
+	dao.parseID = &idParser{
+		typ: reflect.TypeOf(*new(ID)),
+		parse: func(id string) (reflect.Value, error) {
+			parsed, err := parse(id)
+			return reflect.ValueOf(parsed), err
+		},
+	}
+
+	return &TypedDAO[ID, Document]{
+		dao:   dao,
+		parse: parse,
+	}
+}
+
+// DAO gives access to the underlying string-ID DAO, for the operations that
+// do not take IDs.
+func (c *TypedDAO[ID, Document]) DAO() *DAO[Document] {
+	return c.dao
+}
+
+func (c *TypedDAO[ID, Document]) Parse(id string) (ID, error) {
+
+	parsed, err := c.parse(id)
+	if err != nil {
+		var empty ID
+		cause := fmt.Sprintf("(invalid ID '%s': %s)", id, err.Error())
+		return empty, e.Wrap(cause, e.ErrBadRequest)
+	}
+
+	return parsed, nil
+}
+
+func (c *TypedDAO[ID, Document]) Create(ctx context.Context, id ID, document Document) error {
+	return c.dao.Create(ctx, id.String(), document)
+}
+
+func (c *TypedDAO[ID, Document]) Read(ctx context.Context, id ID) (Document, error) {
+	return c.dao.Read(ctx, id.String())
+}
+
+func (c *TypedDAO[ID, Document]) Update(ctx context.Context, id ID, update t.Update) error {
+	return c.dao.Update(ctx, id.String(), update)
+}
+
+func (c *TypedDAO[ID, Document]) Delete(ctx context.Context, id ID) error {
+	return c.dao.Delete(ctx, id.String())
+}
+
+func (c *TypedDAO[ID, Document]) Search(ctx context.Context, queries []t.Query) ([]Document, error) {
+	return c.dao.Search(ctx, queries)
+}
+
+func (c *TypedDAO[ID, Document]) Exists(ctx context.Context, id ID) (bool, error) {
+	return c.dao.Exists(ctx, id.String())
+}
+
+func (c *TypedDAO[ID, Document]) ReadOrCreate(ctx context.Context, id ID, init func() Document) (Document, error) {
+	return c.dao.ReadOrCreate(ctx, id.String(), init)
+}
+
+func (c *TypedDAO[ID, Document]) UpdateIf(ctx context.Context, id ID, queries []t.Query, update t.Update) error {
+	return c.dao.UpdateIf(ctx, id.String(), queries, update)
+}
+
+func (c *TypedDAO[ID, Document]) Adjust(ctx context.Context, id ID, field string, delta, min, max int64) (int64, error) {
+	return c.dao.Adjust(ctx, id.String(), field, delta, min, max)
+}
+
+func (c *TypedDAO[ID, Document]) Load(ctx context.Context, id ID) (Document, error) {
+	return c.dao.Load(ctx, id.String())
+}
+
+func (c *TypedDAO[ID, Document]) BundleDocument(ctx context.Context, b *Bundle, id ID) error {
+	return c.dao.BundleDocument(ctx, b, id.String())
+}
+
+func (c *TypedDAO[ID, Document]) UpdateVersion(ctx context.Context, id ID, version int64, update t.Update) error {
+	return c.dao.UpdateVersion(ctx, id.String(), version, update)
+}
@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type userID struct {
+	tenant, name string
+}
+
+func (id userID) String() string {
+	return id.tenant + ":" + id.name
+}
+
+func parseUserID(id string) (userID, error) {
+
+	tenant, name, found := strings.Cut(id, ":")
+	if !found {
+		return userID{}, fmt.Errorf("missing ':'")
+	}
+
+	return userID{tenant: tenant, name: name}, nil
+}
+
+type user struct {
+	ID   userID `firestore:"-" dao:"id"`
+	Name string `firestore:"name"`
+}
+
+func TestTypedIDs(tt *testing.T) {
+
+	c := NewTypedDAO[userID, user](nil, "users", zap.NewNop().Sugar(), parseUserID)
+
+	d, err := c.dao.created(user{Name: "jeff"}, "acme:jeff", time.Now())
+	if err != nil {
+		tt.Fatalf("unexpected error: %v", err)
+	}
+	if d.ID != (userID{tenant: "acme", name: "jeff"}) {
+		tt.Errorf("got ID %#v", d.ID)
+	}
+
+	_, err = c.dao.created(user{}, "jeff", time.Now())
+	if err == nil {
+		tt.Error("unparsable ID: expected an error")
+	}
+}